@@ -0,0 +1,101 @@
+// Package filters provides otelkit.Filter building blocks. The matchers report
+// whether a request matches, combine them with Not to skip the matching
+// requests:
+//
+//	otelkit.WithFilter(filters.Not(filters.Any(
+//		filters.Path("/healthz"),
+//		filters.UserAgentContains("kube-probe"),
+//	)))
+package filters
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nnnewb/otelkit"
+)
+
+// Path matches requests whose URL path is exactly path.
+func Path(path string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		return req.URL.Path == path
+	}
+}
+
+// PathPrefix matches requests whose URL path starts with prefix.
+func PathPrefix(prefix string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		return strings.HasPrefix(req.URL.Path, prefix)
+	}
+}
+
+// Method matches requests with the given method.
+func Method(method string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		return req.Method == method
+	}
+}
+
+// Header matches requests carrying header name with exactly value.
+func Header(name, value string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		for _, v := range req.Header.Values(name) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HeaderContains matches requests carrying header name with a value that
+// contains substr.
+func HeaderContains(name, substr string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		for _, v := range req.Header.Values(name) {
+			if strings.Contains(v, substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// UserAgentContains matches requests whose User-Agent contains substr, such
+// as "kube-probe" for Kubernetes liveness and readiness probes.
+func UserAgentContains(substr string) otelkit.Filter {
+	return func(req *http.Request) bool {
+		return strings.Contains(req.UserAgent(), substr)
+	}
+}
+
+// Not inverts filter.
+func Not(filter otelkit.Filter) otelkit.Filter {
+	return func(req *http.Request) bool {
+		return !filter(req)
+	}
+}
+
+// All matches requests matched by every filter.
+func All(filters ...otelkit.Filter) otelkit.Filter {
+	return func(req *http.Request) bool {
+		for _, filter := range filters {
+			if !filter(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches requests matched by at least one filter.
+func Any(filters ...otelkit.Filter) otelkit.Filter {
+	return func(req *http.Request) bool {
+		for _, filter := range filters {
+			if filter(req) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package filters_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/filters"
+)
+
+func TestFilters(t *testing.T) {
+	probe := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	probe.Header.Set("User-Agent", "kube-probe/1.27")
+	probe.Header.Add("Accept", "text/plain")
+	probe.Header.Add("Accept", "application/json")
+
+	api := httptest.NewRequest(http.MethodPost, "/api/users?id=1", nil)
+	api.Header.Set("User-Agent", "curl/8.0")
+
+	always := func(*http.Request) bool { return true }
+	never := func(*http.Request) bool { return false }
+
+	for _, tt := range []struct {
+		name   string
+		filter otelkit.Filter
+		probe  bool
+		api    bool
+	}{
+		{"path", filters.Path("/healthz"), true, false},
+		{"path ignores query", filters.Path("/api/users"), false, true},
+		{"path is exact", filters.Path("/health"), false, false},
+		{"path prefix", filters.PathPrefix("/api/"), false, true},
+		{"method", filters.Method(http.MethodPost), false, true},
+		{"header", filters.Header("Accept", "application/json"), true, false},
+		{"header is exact", filters.Header("Accept", "json"), false, false},
+		{"header name is canonical", filters.Header("accept", "text/plain"), true, false},
+		{"header contains", filters.HeaderContains("Accept", "json"), true, false},
+		{"missing header", filters.HeaderContains("Authorization", ""), false, false},
+		{"user agent", filters.UserAgentContains("kube-probe"), true, false},
+		{"not", filters.Not(filters.Path("/healthz")), false, true},
+		{"all", filters.All(filters.Method(http.MethodGet), filters.PathPrefix("/health")), true, false},
+		{"all of none", filters.All(), true, true},
+		{"all short-circuits", filters.All(never, func(*http.Request) bool {
+			t.Error("All called a filter after one failed")
+			return true
+		}), false, false},
+		{"any", filters.Any(filters.Path("/healthz"), filters.Method(http.MethodPost)), true, true},
+		{"any of none", filters.Any(), false, false},
+		{"any short-circuits", filters.Any(always, func(*http.Request) bool {
+			t.Error("Any called a filter after one matched")
+			return false
+		}), true, true},
+		{"skip probes", filters.Not(filters.Any(
+			filters.Path("/healthz"),
+			filters.UserAgentContains("kube-probe"),
+		)), false, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(probe); got != tt.probe {
+				t.Errorf("probe request: got %v, want %v", got, tt.probe)
+			}
+			if got := tt.filter(api); got != tt.api {
+				t.Errorf("api request: got %v, want %v", got, tt.api)
+			}
+		})
+	}
+}
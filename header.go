@@ -0,0 +1,171 @@
+package otelkit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RedactedValue replaces the value of headers configured by WithRedactedHeaders.
+const RedactedValue = "[REDACTED]"
+
+// DefaultSensitiveHeaders are never captured unless explicitly named by
+// WithHeaderAllowlist, WithRedactedHeaders or WithHashedHeaders.
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+type headerCapture struct {
+	allow     map[string]struct{}
+	sensitive map[string]struct{}
+	deny      map[string]struct{}
+	redact    map[string]struct{}
+	hash      map[string]struct{}
+	maxValue  int
+}
+
+func newHeaderCapture() headerCapture {
+	return headerCapture{
+		sensitive: headerSet(nil, DefaultSensitiveHeaders...),
+		deny:      map[string]struct{}{},
+		redact:    map[string]struct{}{},
+		hash:      map[string]struct{}{},
+	}
+}
+
+func headerSet(set map[string]struct{}, names ...string) map[string]struct{} {
+	if set == nil {
+		set = make(map[string]struct{}, len(names))
+	}
+	for _, name := range names {
+		set[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	return set
+}
+
+// explicit removes names from the default sensitive headers, they were asked
+// for by name so the user opted in.
+func (h *headerCapture) explicit(names ...string) {
+	for _, name := range names {
+		delete(h.sensitive, http.CanonicalHeaderKey(name))
+	}
+}
+
+// WithHeaderAllowlist captures only the named headers. Calling it without
+// names disables header capture.
+func WithHeaderAllowlist(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.headers.allow = headerSet(cfg.headers.allow, names...)
+		cfg.headers.explicit(names...)
+	}
+}
+
+// WithHeaderDenylist never captures the named headers, in addition to
+// DefaultSensitiveHeaders.
+func WithHeaderDenylist(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.headers.deny = headerSet(cfg.headers.deny, names...)
+	}
+}
+
+// WithRedactedHeaders captures the named headers with their value replaced by
+// RedactedValue, so only their presence is recorded.
+func WithRedactedHeaders(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.headers.redact = headerSet(cfg.headers.redact, names...)
+		cfg.headers.explicit(names...)
+	}
+}
+
+// WithHashedHeaders captures the named headers with their value replaced by
+// its SHA-256 digest, so equal values can still be correlated.
+func WithHashedHeaders(names ...string) Option {
+	return func(cfg *Config) {
+		cfg.headers.hash = headerSet(cfg.headers.hash, names...)
+		cfg.headers.explicit(names...)
+	}
+}
+
+// WithHeaderValueLimit truncates captured header values to at most n bytes.
+// Redacted and hashed values are not truncated. n <= 0 means no limit.
+func WithHeaderValueLimit(n int) Option {
+	return func(cfg *Config) {
+		cfg.headers.maxValue = n
+	}
+}
+
+// RequestHeaderAttributes returns the http.request.header.* attributes of h
+// allowed by the configuration.
+func (c *Config) RequestHeaderAttributes(h http.Header) []attribute.KeyValue {
+	return c.headers.attributes("http.request.header.", h)
+}
+
+// ResponseHeaderAttributes returns the http.response.header.* attributes of h
+// allowed by the configuration.
+func (c *Config) ResponseHeaderAttributes(h http.Header) []attribute.KeyValue {
+	return c.headers.attributes("http.response.header.", h)
+}
+
+// RequestMetadataAttributes returns the rpc.grpc.request.metadata.*
+// attributes of the gRPC metadata md, filtered by the same rules as headers.
+func (c *Config) RequestMetadataAttributes(md map[string][]string) []attribute.KeyValue {
+	return c.headers.attributes("rpc.grpc.request.metadata.", md)
+}
+
+// ResponseMetadataAttributes returns the rpc.grpc.response.metadata.*
+// attributes of the gRPC metadata md, filtered by the same rules as headers.
+func (c *Config) ResponseMetadataAttributes(md map[string][]string) []attribute.KeyValue {
+	return c.headers.attributes("rpc.grpc.response.metadata.", md)
+}
+
+func (h *headerCapture) attributes(prefix string, header http.Header) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(header))
+	for key, values := range header {
+		name := http.CanonicalHeaderKey(key)
+		if h.allow != nil {
+			if _, ok := h.allow[name]; !ok {
+				continue
+			}
+		}
+		if _, ok := h.sensitive[name]; ok {
+			continue
+		}
+		if _, ok := h.deny[name]; ok {
+			continue
+		}
+
+		captured := make([]string, len(values))
+		for i, value := range values {
+			if _, ok := h.redact[name]; ok {
+				captured[i] = RedactedValue
+			} else if _, ok := h.hash[name]; ok {
+				sum := sha256.Sum256([]byte(value))
+				captured[i] = "sha256:" + hex.EncodeToString(sum[:])
+			} else {
+				captured[i] = truncate(value, h.maxValue)
+			}
+		}
+		attrs = append(attrs, attribute.String(prefix+key, strings.Join(captured, "\n")))
+	}
+	return attrs
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	// don't cut a multibyte character in half
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,120 @@
+package otelkit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestHeaderAttributes(t *testing.T) {
+	digest := sha256.Sum256([]byte("Bearer secret"))
+	header := http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"session=1"},
+		"Set-Cookie":    {"session=1; HttpOnly"},
+		"X-Api-Key":     {"key"},
+		"Accept":        {"text/html", "application/json"},
+		"X-Request-Id":  {"42"},
+		"X-Name":        {"zoë"},
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{"sensitive headers dropped by default", nil, map[string]string{
+			"Accept":       "text/html\napplication/json",
+			"X-Request-Id": "42",
+			"X-Name":       "zoë",
+		}},
+		{"allowlist", []Option{WithHeaderAllowlist("x-request-id", "Cookie")}, map[string]string{
+			"X-Request-Id": "42",
+			"Cookie":       "session=1",
+		}},
+		{"empty allowlist", []Option{WithHeaderAllowlist()}, map[string]string{}},
+		{"allowlist keeps other sensitive headers out", []Option{WithHeaderAllowlist("Authorization", "Set-Cookie", "X-Api-Key")}, map[string]string{
+			"Authorization": "Bearer secret",
+			"Set-Cookie":    "session=1; HttpOnly",
+			"X-Api-Key":     "key",
+		}},
+		{"denylist", []Option{WithHeaderDenylist("accept", "X-Name")}, map[string]string{
+			"X-Request-Id": "42",
+		}},
+		{"denylist wins over allowlist", []Option{WithHeaderAllowlist("Accept", "X-Name"), WithHeaderDenylist("X-Name")}, map[string]string{
+			"Accept": "text/html\napplication/json",
+		}},
+		{"redacted", []Option{WithRedactedHeaders("cookie", "Accept")}, map[string]string{
+			"Accept":       RedactedValue + "\n" + RedactedValue,
+			"Cookie":       RedactedValue,
+			"X-Request-Id": "42",
+			"X-Name":       "zoë",
+		}},
+		{"hashed", []Option{WithHashedHeaders("Authorization"), WithHeaderAllowlist("Authorization")}, map[string]string{
+			"Authorization": "sha256:" + hex.EncodeToString(digest[:]),
+		}},
+		{"truncated", []Option{WithHeaderValueLimit(3)}, map[string]string{
+			"Accept":       "tex\napp",
+			"X-Request-Id": "42",
+			// ë is two bytes, cutting after its first one would leave
+			// invalid UTF-8
+			"X-Name": "zo",
+		}},
+		{"redacted and hashed values are not truncated", []Option{WithHeaderValueLimit(4), WithHeaderAllowlist("Cookie", "Authorization"), WithRedactedHeaders("Cookie"), WithHashedHeaders("Authorization")}, map[string]string{
+			"Authorization": "sha256:" + hex.EncodeToString(digest[:]),
+			"Cookie":        RedactedValue,
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := attributeMap(NewConfig(tt.opts...).RequestHeaderAttributes(header), "http.request.header.")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataAttributes(t *testing.T) {
+	md := map[string][]string{
+		"authorization": {"Bearer secret"},
+		"x-request-id":  {"42"},
+	}
+
+	got := attributeMap(NewConfig().RequestMetadataAttributes(md), "rpc.grpc.request.metadata.")
+	if want := map[string]string{"x-request-id": "42"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func attributeMap(attrs []attribute.KeyValue, prefix string) map[string]string {
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	m := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		m[string(kv.Key)[len(prefix):]] = kv.Value.AsString()
+	}
+	return m
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 0, "hello"},
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"日本語", 4, "日"},
+		{"日本語", 6, "日本"},
+		{"日本語", 2, ""},
+	} {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
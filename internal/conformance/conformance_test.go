@@ -0,0 +1,275 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/filters"
+	metricgin "github.com/nnnewb/otelkit/metric/gin"
+	metrichttp "github.com/nnnewb/otelkit/metric/http"
+	metrickit "github.com/nnnewb/otelkit/metric/kit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracegin "github.com/nnnewb/otelkit/tracing/gin"
+	tracehttp "github.com/nnnewb/otelkit/tracing/http"
+	tracekit "github.com/nnnewb/otelkit/tracing/kit"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// route is served by every adapter. go-kit learns it from the operation name,
+// gin from its router and net/http from its ServeMux, see muxOptions.
+const route = "/users"
+
+// response is what the handlers answer.
+type response struct {
+	status int
+	body   string
+	// err fails the go-kit endpoint, the other adapters answer what go-kit's
+	// DefaultErrorEncoder writes for it
+	err error
+}
+
+func (r response) write(w http.ResponseWriter) {
+	if r.err != nil {
+		khttp.DefaultErrorEncoder(context.Background(), r.err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(r.status)
+	_, _ = io.WriteString(w, r.body)
+}
+
+// statusError is an error go-kit answers with its own status code and
+// headers.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e statusError) Error() string        { return e.msg }
+func (e statusError) StatusCode() int      { return e.status }
+func (e statusError) Headers() http.Header { return http.Header{"Retry-After": {"10"}} }
+
+// servers build the same instrumented server with each adapter, passing opts
+// to every middleware. Servers which don't route requests themselves are left
+// out of the unmatched cases.
+var servers = []struct {
+	name   string
+	routes bool
+	new    func(h *otelkittest.Harness, resp response, opts ...otelkit.Option) http.Handler
+}{
+	{"net/http", muxRoutes, func(h *otelkittest.Harness, resp response, opts ...otelkit.Option) http.Handler {
+		opts = append(h.Options(muxOptions...), opts...)
+		mux := http.NewServeMux()
+		mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			resp.write(w)
+		})
+		return tracehttp.NewTraceHandler(opts...)(metrichttp.NewMeasureHandler(opts...)(mux))
+	}},
+	{"gin", true, func(h *otelkittest.Harness, resp response, opts ...otelkit.Option) http.Handler {
+		opts = h.Options(opts...)
+		r := gin.New()
+		r.Use(tracegin.NewTraceMiddleware(opts...), metricgin.NewMeasureHandleFunc(opts...))
+		r.Any(route, func(c *gin.Context) {
+			_, _ = io.Copy(io.Discard, c.Request.Body)
+			resp.write(c.Writer)
+		})
+		// answer like ServeMux
+		r.NoRoute(gin.WrapF(http.NotFound))
+		return r
+	}},
+	{"go-kit", false, func(h *otelkittest.Harness, resp response, opts ...otelkit.Option) http.Handler {
+		opts = append(h.Options(otelkit.WithOperationName(route)), opts...)
+		return khttp.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { return resp, resp.err },
+			func(_ context.Context, r *http.Request) (interface{}, error) {
+				_, err := io.Copy(io.Discard, r.Body)
+				return nil, err
+			},
+			func(_ context.Context, w http.ResponseWriter, v interface{}) error {
+				v.(response).write(w)
+				return nil
+			},
+			tracekit.NewTraceServerBefore(opts...),
+			tracekit.TraceServerAfter(opts...),
+			tracekit.TraceServerFinalizer(opts...),
+			metrickit.NewMeasureServerBefore(opts...),
+			metrickit.NewMeasureServerFinalizer(opts...))
+	}},
+}
+
+// clients send the same request with each adapter, against an uninstrumented
+// server.
+var clients = []struct {
+	name string
+	do   func(h *otelkittest.Harness, req *http.Request) error
+}{
+	{"net/http", func(h *otelkittest.Harness, req *http.Request) error {
+		opts := h.Options()
+		client := &http.Client{Transport: tracehttp.TraceTransport(opts...)(metrichttp.MeasureTransport(opts...)(nil))}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.Body.Close()
+	}},
+	{"go-kit", func(h *otelkittest.Harness, req *http.Request) error {
+		opts := h.Options()
+		client := khttp.NewClient(req.Method, req.URL,
+			func(_ context.Context, r *http.Request, _ interface{}) error {
+				for key, values := range req.Header {
+					r.Header[key] = values
+				}
+				r.Body, r.ContentLength = req.Body, req.ContentLength
+				return nil
+			},
+			func(_ context.Context, resp *http.Response) (interface{}, error) {
+				_, err := io.Copy(io.Discard, resp.Body)
+				return nil, err
+			},
+			tracekit.NewTraceClientBefore(opts...),
+			tracekit.TraceClientAfter(opts...),
+			tracekit.TraceClientFinalizer(opts...),
+			metrickit.MeasureClientBefore(opts...),
+			metrickit.MeasureClientAfter(opts...),
+			metrickit.MeasureClientFinalizer(opts...))
+		_, err := client.Endpoint()(context.Background(), nil)
+		return err
+	}},
+}
+
+var cases = []struct {
+	name   string
+	method string
+	path   string
+	body   string
+	header http.Header
+	resp   response
+}{
+	{"ok", http.MethodGet, route, "", nil, response{status: http.StatusOK, body: "[]"}},
+	{"created", http.MethodPost, route, `{"name":"x"}`, http.Header{"Content-Type": {"application/json"}}, response{status: http.StatusCreated, body: "{}"}},
+	{"not found", http.MethodGet, route, "", nil, response{status: http.StatusNotFound, body: "not found"}},
+	{"server error", http.MethodDelete, route, "", nil, response{status: http.StatusInternalServerError, body: "oops"}},
+	{"propagated", http.MethodGet, route, "", http.Header{
+		"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"User-Agent":  {"conformance"},
+	}, response{status: http.StatusOK}},
+	{"endpoint error", http.MethodGet, route, "", nil, response{
+		status: http.StatusInternalServerError, err: errors.New("db down")}},
+	{"status error", http.MethodPut, route, `{}`, nil, response{
+		status: http.StatusServiceUnavailable, err: statusError{http.StatusServiceUnavailable, "draining"}}},
+	{"unmatched", http.MethodGet, "/nowhere", "", nil, response{status: http.StatusNotFound, body: "404 page not found\n"}},
+}
+
+func newRequest(target, method, body string, header http.Header) *http.Request {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, r)
+	if r != nil {
+		req.ContentLength = int64(len(body))
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return req
+}
+
+func TestServers(t *testing.T) {
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []byte
+			var first string
+			for _, server := range servers {
+				if tt.path != route && !server.routes {
+					continue
+				}
+				h := otelkittest.New(t)
+				rec := httptest.NewRecorder()
+				server.new(h, tt.resp).ServeHTTP(rec, newRequest(tt.path, tt.method, tt.body, tt.header))
+				if rec.Code != tt.resp.status {
+					t.Fatalf("%s answered %d, want %d", server.name, rec.Code, tt.resp.status)
+				}
+
+				got := h.Snapshot(t, otelkittest.IgnoreScope())
+				if want == nil {
+					want, first = got, server.name
+					continue
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs from %s:\n%s", server.name, first, otelkittest.Diff(string(want), string(got)))
+				}
+			}
+		})
+	}
+}
+
+func TestServersFiltered(t *testing.T) {
+	filter := otelkit.WithFilter(filters.Not(filters.Path("/healthz")))
+	for _, server := range servers {
+		t.Run(server.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			handler := server.new(h, response{status: http.StatusOK}, filter)
+			handler.ServeHTTP(httptest.NewRecorder(), newRequest("/healthz", http.MethodGet, "", nil))
+
+			h.RequireSpanCount(t, 0)
+			for _, sm := range h.Collect(t).ScopeMetrics {
+				for _, m := range sm.Metrics {
+					t.Errorf("%s recorded %s", sm.Scope.Name, m.Name)
+				}
+			}
+
+			// the filter lets the other requests through
+			handler.ServeHTTP(httptest.NewRecorder(), newRequest(route, http.MethodGet, "", nil))
+			h.RequireSpanCount(t, 1)
+		})
+	}
+}
+
+func TestClients(t *testing.T) {
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				tt.resp.write(w)
+			}))
+			defer srv.Close()
+			u, _ := url.Parse(srv.URL + tt.path)
+
+			var want []byte
+			for i, client := range clients {
+				h := otelkittest.New(t)
+				req := newRequest(u.String(), tt.method, tt.body, tt.header)
+				req.RequestURI = ""
+				if err := client.do(h, req); err != nil {
+					t.Fatalf("%s: %v", client.name, err)
+				}
+
+				// the connection to srv is new for the first client only
+				got := h.Snapshot(t, otelkittest.IgnoreScope(), otelkittest.ScrubAttributes("http.connection.reused"))
+				if i == 0 {
+					want = got
+					continue
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs from %s:\n%s", client.name, clients[0].name, otelkittest.Diff(string(want), string(got)))
+				}
+			}
+		})
+	}
+}
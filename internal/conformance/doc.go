@@ -0,0 +1,4 @@
+// Package conformance holds the test driving the same requests through the
+// net/http, gin and go-kit adapters and checking they emit identical spans and
+// metrics.
+package conformance
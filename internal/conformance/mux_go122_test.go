@@ -0,0 +1,13 @@
+//go:build go1.22
+
+//go:debug httpmuxgo121=0
+
+package conformance
+
+import "github.com/nnnewb/otelkit"
+
+// muxRoutes tells that ServeMux reports the pattern it matched, the net/http
+// server needs no operation name.
+const muxRoutes = true
+
+var muxOptions []otelkit.Option
@@ -0,0 +1,11 @@
+//go:build !go1.22
+
+package conformance
+
+import "github.com/nnnewb/otelkit"
+
+// muxRoutes tells that ServeMux doesn't report the pattern it matched before
+// go1.22, the net/http server falls back to the operation name.
+const muxRoutes = false
+
+var muxOptions = []otelkit.Option{otelkit.WithOperationName(route)}
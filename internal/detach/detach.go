@@ -0,0 +1,27 @@
+// Package detach separates contexts from their cancellation, so measurements
+// of canceled requests still get recorded.
+package detach
+
+import (
+	"context"
+	"time"
+)
+
+// Context returns a context keeping the values of ctx but not its
+// cancellation. The SDK drops measurements made with a canceled context: a
+// client disconnecting mid-request, or go-kit canceling its client context
+// before running the finalizers, must not lose them.
+func Context(ctx context.Context) context.Context {
+	if _, ok := ctx.(detached); ok {
+		return ctx
+	}
+	return detached{ctx}
+}
+
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
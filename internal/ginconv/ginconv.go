@@ -0,0 +1,26 @@
+// Package ginconv derives attribute values from gin types.
+package ginconv
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrorTypeKey is the attribute holding the gin.ErrorType of an error.
+const ErrorTypeKey = attribute.Key("gin.error.type")
+
+// ErrorType names the type of err: bind, render, public, private or other.
+func ErrorType(err *gin.Error) string {
+	switch {
+	case err.Type&gin.ErrorTypeBind != 0:
+		return "bind"
+	case err.Type&gin.ErrorTypeRender != 0:
+		return "render"
+	case err.Type&gin.ErrorTypePublic != 0:
+		return "public"
+	case err.Type&gin.ErrorTypePrivate != 0:
+		return "private"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,85 @@
+// Package httpconv derives the attributes of HTTP spans and metrics from
+// requests and responses, so every adapter emits the same telemetry. Values
+// used by metrics are bounded.
+package httpconv
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Other replaces values outside of a known set, keeping attribute cardinality
+// bounded.
+const Other = "_OTHER"
+
+var knownMethods = map[string]struct{}{
+	http.MethodConnect: {},
+	http.MethodDelete:  {},
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodOptions: {},
+	http.MethodPatch:   {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodTrace:   {},
+}
+
+// Method returns the request method, or Other for non-standard methods.
+func Method(req *http.Request) string {
+	if _, ok := knownMethods[req.Method]; ok {
+		return req.Method
+	}
+	return Other
+}
+
+// Scheme returns the scheme the request was received or sent with.
+func Scheme(req *http.Request) string {
+	if req.URL != nil && req.URL.Scheme != "" {
+		return req.URL.Scheme
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Route returns route, or Other when the request matched no route.
+func Route(route string) string {
+	if route == "" {
+		return Other
+	}
+	return route
+}
+
+// Host returns the host name a client request is sent to.
+func Host(req *http.Request) string {
+	if req.URL != nil && req.URL.Host != "" {
+		return req.URL.Hostname()
+	}
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		return req.Host
+	}
+	return host
+}
+
+// Port returns the port a client request is sent to, defaulting to the port
+// of its scheme. It returns 0 when unknown.
+func Port(req *http.Request) int {
+	var port string
+	if req.URL != nil {
+		port = req.URL.Port()
+	}
+	if port == "" {
+		switch Scheme(req) {
+		case "http":
+			return 80
+		case "https":
+			return 443
+		}
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
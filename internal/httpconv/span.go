@@ -0,0 +1,92 @@
+package httpconv
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ServerRequest returns the attributes every server span carries for req.
+func ServerRequest(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.method", req.Method),
+		attribute.Int64("http.request_content_length", req.ContentLength),
+		attribute.String("net.protocol.name", "http"),
+		attribute.String("net.protocol.version", ProtocolVersion(req.ProtoMajor, req.ProtoMinor)),
+	}
+	if host, port, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		attrs = append(attrs, attribute.String("net.sock.peer.addr", host))
+		if n, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, attribute.Int("net.sock.peer.port", n))
+		}
+	} else if req.RemoteAddr != "" {
+		attrs = append(attrs, attribute.String("net.sock.peer.addr", req.RemoteAddr))
+	}
+	if ua := req.UserAgent(); ua != "" {
+		attrs = append(attrs, attribute.String("user_agent.original", ua))
+	}
+	return attrs
+}
+
+// ServerRoute returns http.route for route, the route template or operation
+// name of a request, when it is a path.
+func ServerRoute(route string) []attribute.KeyValue {
+	if !strings.HasPrefix(route, "/") {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("http.route", route)}
+}
+
+// ServerResponse returns the attributes a server span gets once the response
+// is written. size is the number of response body bytes written.
+func ServerResponse(status int, size int64) []attribute.KeyValue {
+	if size < 0 {
+		size = 0
+	}
+	return []attribute.KeyValue{
+		attribute.Int("http.status_code", status),
+		attribute.Int64("http.response_content_length", size),
+	}
+}
+
+// ClientRequest returns the attributes every client span carries for req.
+// Credentials are removed from http.url.
+func ClientRequest(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.method", req.Method),
+		attribute.Int64("http.request_content_length", req.ContentLength),
+		attribute.String("net.protocol.name", "http"),
+		attribute.String("net.protocol.version", ProtocolVersion(req.ProtoMajor, req.ProtoMinor)),
+		attribute.String("net.peer.name", Host(req)),
+	}
+	if port := Port(req); port != 0 {
+		attrs = append(attrs, attribute.Int("net.peer.port", port))
+	}
+	if req.URL != nil {
+		u := *req.URL
+		u.User = nil
+		attrs = append(attrs, attribute.String("http.url", u.String()))
+	}
+	return attrs
+}
+
+// ClientResponse returns the attributes a client span gets from resp.
+func ClientResponse(resp *http.Response) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.Int("http.status_code", resp.StatusCode)}
+	if resp.ContentLength >= 0 {
+		attrs = append(attrs, attribute.Int64("http.response_content_length", resp.ContentLength))
+	}
+	return attrs
+}
+
+// ProtocolVersion formats an HTTP version the way net.protocol.version
+// expects it: "1.0", "1.1", "2" or "3".
+func ProtocolVersion(major, minor int) string {
+	if major >= 2 && minor == 0 {
+		return strconv.Itoa(major)
+	}
+	return strconv.Itoa(major) + "." + strconv.Itoa(minor)
+}
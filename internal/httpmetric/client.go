@@ -0,0 +1,205 @@
+package httpmetric
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Client holds the http.client.* instruments.
+type Client struct {
+	cfg        *otelkit.Config
+	classifier otelkit.StatusClassifier
+
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+	errors       metric.Int64Counter
+	connections  metric.Int64Counter
+}
+
+// NewClient creates the client instruments with meter. It panics if an
+// instrument can not be created.
+func NewClient(meter metric.Meter, cfg *otelkit.Config) *Client {
+	c := &Client{cfg: cfg, classifier: cfg.Classifier(otelkit.ClientStatus)}
+	var err error
+
+	c.duration, err = meter.Float64Histogram(
+		"http.client.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP client requests."))
+	if err != nil {
+		panic(err)
+	}
+
+	c.active, err = meter.Int64UpDownCounter(
+		"http.client.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP client requests."))
+	if err != nil {
+		panic(err)
+	}
+
+	c.requestSize, err = meter.Int64Histogram(
+		"http.client.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP client request bodies."))
+	if err != nil {
+		panic(err)
+	}
+
+	c.responseSize, err = meter.Int64Histogram(
+		"http.client.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP client response bodies."))
+	if err != nil {
+		panic(err)
+	}
+
+	c.errors, err = meter.Int64Counter(
+		"http.client.request.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Number of HTTP client requests that failed."))
+	if err != nil {
+		panic(err)
+	}
+
+	c.connections, err = meter.Int64Counter(
+		"http.client.connections",
+		metric.WithUnit("{connection}"),
+		metric.WithDescription("Number of connections obtained by HTTP client requests, new or reused."))
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// ClientMeasurement is an outbound request in flight.
+type ClientMeasurement struct {
+	client *Client
+	ctx    context.Context
+	req    *http.Request
+	start  time.Time
+	status int
+	resp   *http.Response
+	body   *countingBody
+	server []attribute.KeyValue
+}
+
+// Begin marks req as active and starts timing it.
+func (c *Client) Begin(ctx context.Context, req *http.Request) *ClientMeasurement {
+	m := &ClientMeasurement{
+		client: c,
+		ctx:    detach.Context(ctx),
+		req:    req,
+		start:  time.Now(),
+		server: serverAttributes(req),
+	}
+	c.active.Add(m.ctx, 1, metric.WithAttributes(m.server...))
+	return m
+}
+
+// ClientTrace returns ctx with an httptrace.ClientTrace counting whether the
+// connection used by the request was reused. Send the request with the
+// returned context.
+func (m *ClientMeasurement) ClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			m.client.connections.Add(m.ctx, 1, metric.WithAttributes(
+				append(m.server[:len(m.server):len(m.server)], attribute.Bool("http.connection.reused", info.Reused))...))
+		},
+	})
+}
+
+// Response notes the response of the request. resp.Body is replaced to count
+// the response body size when it is not announced.
+func (m *ClientMeasurement) Response(resp *http.Response) {
+	m.resp = resp
+	m.status = resp.StatusCode
+	if resp.ContentLength < 0 && resp.Body != nil && resp.Body != http.NoBody {
+		m.body = &countingBody{ReadCloser: resp.Body}
+		resp.Body = m.body
+	}
+}
+
+// End records the finished request. err is the transport error, if any.
+func (m *ClientMeasurement) End(err error) {
+	c := m.client
+	elapsed := time.Since(m.start)
+	c.active.Add(m.ctx, -1, metric.WithAttributes(m.server...))
+
+	attrs := m.attributes()
+	c.duration.Record(m.ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+	c.requestSize.Record(m.ctx, nonNegative(m.req.ContentLength), metric.WithAttributes(attrs...))
+	if m.resp != nil {
+		c.responseSize.Record(m.ctx, m.responseSize(), metric.WithAttributes(attrs...))
+	}
+
+	if code, _ := c.classifier(m.status, err); code == codes.Error {
+		errorType := strconv.Itoa(m.status)
+		if err != nil {
+			errorType = fmt.Sprintf("%T", err)
+		}
+		c.errors.Add(m.ctx, 1, metric.WithAttributes(
+			append(attrs, attribute.String("error.type", errorType))...))
+	}
+}
+
+// attributes follows the HTTP client semantic conventions, keyed by the
+// target host instead of the route.
+func (m *ClientMeasurement) attributes() []attribute.KeyValue {
+	req := m.req
+	attrs := append([]attribute.KeyValue(nil), m.server...)
+	if m.status != 0 {
+		attrs = append(attrs, attribute.Int("http.response.status_code", m.status))
+	}
+	attrs = append(attrs, m.client.cfg.Attributes...)
+	if m.client.cfg.MetricAttributesFunc != nil {
+		attrs = append(attrs, m.client.cfg.MetricAttributesFunc(req)...)
+	}
+	return attrs
+}
+
+// serverAttributes identify the target of req.
+func serverAttributes(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", httpconv.Method(req)),
+		attribute.String("server.address", httpconv.Host(req)),
+	}
+	if port := httpconv.Port(req); port != 0 {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+	return attrs
+}
+
+// responseSize prefers the announced Content-Length, falling back to what the
+// caller read.
+func (m *ClientMeasurement) responseSize() int64 {
+	if m.resp.ContentLength >= 0 {
+		return m.resp.ContentLength
+	}
+	if m.body == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&m.body.n)
+}
+
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+// Package httpmetric records the HTTP server metrics shared by the metric
+// middlewares.
+package httpmetric
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Server holds the http.server.* instruments.
+type Server struct {
+	cfg *otelkit.Config
+
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+
+	// legacy instruments, only created with otelkit.WithLegacyMetrics
+	legacyCount    metric.Int64Counter
+	legacyDuration metric.Int64Histogram
+}
+
+// NewServer creates the server instruments with meter. It panics if an
+// instrument can not be created.
+func NewServer(meter metric.Meter, cfg *otelkit.Config) *Server {
+	s := &Server{cfg: cfg}
+	var err error
+
+	s.duration, err = meter.Float64Histogram(
+		"http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."))
+	if err != nil {
+		panic(err)
+	}
+
+	s.active, err = meter.Int64UpDownCounter(
+		"http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP server requests."))
+	if err != nil {
+		panic(err)
+	}
+
+	s.requestSize, err = meter.Int64Histogram(
+		"http.server.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server request bodies."))
+	if err != nil {
+		panic(err)
+	}
+
+	s.responseSize, err = meter.Int64Histogram(
+		"http.server.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server response bodies."))
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.LegacyMetrics {
+		// throughput
+		s.legacyCount, err = meter.Int64Counter("request-count")
+		if err != nil {
+			panic(err)
+		}
+
+		// request duration
+		s.legacyDuration, err = meter.Int64Histogram("request-duration-milli")
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return s
+}
+
+// Measurement is a request in flight.
+type Measurement struct {
+	server *Server
+	ctx    context.Context
+	req    *http.Request
+	start  time.Time
+	body   *countingBody
+	active metric.MeasurementOption
+}
+
+// Begin marks req as active and starts timing it. req.Body is replaced to
+// count the request body size.
+func (s *Server) Begin(req *http.Request) *Measurement {
+	m := &Measurement{
+		server: s,
+		ctx:    detach.Context(req.Context()),
+		req:    req,
+		start:  time.Now(),
+		active: metric.WithAttributes(
+			attribute.String("http.request.method", httpconv.Method(req)),
+			attribute.String("url.scheme", httpconv.Scheme(req))),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		m.body = &countingBody{ReadCloser: req.Body}
+		req.Body = m.body
+	}
+	s.active.Add(m.ctx, 1, m.active)
+	return m
+}
+
+// End records the finished request. route is the route template the request
+// matched, empty if unknown. responseSize is the number of response body bytes
+// written.
+func (m *Measurement) End(route string, status int, responseSize int64) {
+	s := m.server
+	elapsed := time.Since(m.start)
+	s.active.Add(m.ctx, -1, m.active)
+
+	attrs := metric.WithAttributes(s.cfg.MetricAttributes(m.req, route, status)...)
+	s.duration.Record(m.ctx, elapsed.Seconds(), attrs)
+	s.requestSize.Record(m.ctx, m.requestSize(), attrs)
+	if responseSize < 0 {
+		responseSize = 0
+	}
+	s.responseSize.Record(m.ctx, responseSize, attrs)
+
+	if s.cfg.LegacyMetrics {
+		legacy := metric.WithAttributes(s.cfg.LegacyMetricAttributes(m.req, route, status)...)
+		s.legacyCount.Add(m.ctx, 1, legacy)
+		s.legacyDuration.Record(m.ctx, elapsed.Milliseconds(), legacy)
+	}
+}
+
+// requestSize prefers the announced Content-Length, falling back to what the
+// handler read for chunked bodies.
+func (m *Measurement) requestSize() int64 {
+	if m.req.ContentLength >= 0 {
+		return m.req.ContentLength
+	}
+	if m.body == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&m.body.n)
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,127 @@
+// Package kitphase tracks which phase of a go-kit server call, decoding,
+// the endpoint or encoding, is running, so errors reported by go-kit's
+// ErrorHandler and ErrorEncoder can be attributed to it.
+package kitphase
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Key is the attribute holding the phase of an error.
+const Key = attribute.Key("gokit.error.phase")
+
+// Phases of a server call. A call is in the unknown phase until a wrapped
+// decoder or endpoint tells where it is.
+const (
+	Unknown  = "unknown"
+	Decode   = "decode"
+	Endpoint = "endpoint"
+	Encode   = "encode"
+)
+
+type keyT struct{}
+
+var key keyT
+
+type tracker struct {
+	mu    sync.Mutex
+	phase string
+	seen  map[string]bool
+}
+
+// Start returns ctx tracking a call in the unknown phase, or ctx itself if it
+// already tracks one.
+func Start(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(key).(*tracker); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, key, &tracker{phase: Unknown})
+}
+
+// Decoding moves the call tracked by ctx to decoding and returns the func to
+// call with the decoder's error once it returns. A decoded request leaves the
+// call in the unknown phase again, up to the endpoint.
+func Decoding(ctx context.Context) (done func(err error)) {
+	t, ok := ctx.Value(key).(*tracker)
+	if !ok {
+		return func(error) {}
+	}
+	t.mu.Lock()
+	t.phase = Decode
+	t.mu.Unlock()
+	return func(err error) {
+		if err == nil {
+			t.mu.Lock()
+			t.phase = Unknown
+			t.mu.Unlock()
+		}
+	}
+}
+
+// EnterEndpoint moves the call tracked by ctx to the endpoint and returns the
+// func to call with the endpoint's error once it returns. Endpoints invoked
+// by the endpoint, sharing its context, leave the phase alone.
+func EnterEndpoint(ctx context.Context) (leave func(err error)) {
+	t, ok := ctx.Value(key).(*tracker)
+	if !ok {
+		return func(error) {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == Endpoint || t.phase == Encode {
+		return func(error) {}
+	}
+	t.phase = Endpoint
+	return func(err error) {
+		// go-kit only goes on to encoding when the endpoint succeeded
+		if err == nil {
+			t.mu.Lock()
+			t.phase = Encode
+			t.mu.Unlock()
+		}
+	}
+}
+
+// Encoding moves the call tracked by ctx to encoding.
+func Encoding(ctx context.Context) {
+	if t, ok := ctx.Value(key).(*tracker); ok {
+		t.mu.Lock()
+		t.phase = Encode
+		t.mu.Unlock()
+	}
+}
+
+// Phase returns the phase of the call tracked by ctx, unknown when untracked.
+func Phase(ctx context.Context) string {
+	t, ok := ctx.Value(key).(*tracker)
+	if !ok {
+		return Unknown
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.phase
+}
+
+// First reports whether consumer sees the error of the call for the first
+// time. go-kit hands the one error ending a call to both the ErrorHandler and
+// the ErrorEncoder, it must be recorded once. It is always true for untracked
+// calls.
+func First(ctx context.Context, consumer string) bool {
+	t, ok := ctx.Value(key).(*tracker)
+	if !ok {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen == nil {
+		t.seen = map[string]bool{}
+	}
+	if t.seen[consumer] {
+		return false
+	}
+	t.seen[consumer] = true
+	return true
+}
@@ -0,0 +1,89 @@
+// Package lbconv numbers the attempts of a go-kit lb.Retry call, shared by the
+// tracing and metric wrappers so they can be stacked.
+package lbconv
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/endpoint"
+	kitlb "github.com/go-kit/kit/sd/lb"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// InstanceKey is the sd instance an attempt was sent to.
+	InstanceKey = attribute.Key("gokit.lb.instance")
+	// AttemptKey is the 1-based number of an attempt.
+	AttemptKey = attribute.Key("gokit.lb.attempt")
+	// AttemptsKey is the number of attempts a call took.
+	AttemptsKey = attribute.Key("gokit.lb.attempts")
+)
+
+type callKeyT struct{}
+
+type attemptKeyT struct{}
+
+var (
+	callKey    callKeyT
+	attemptKey attemptKeyT
+)
+
+type call struct {
+	attempts int32
+}
+
+type attempt struct {
+	n          int
+	noEndpoint bool
+}
+
+// Start returns ctx counting the attempts of a call, or ctx itself if it
+// already counts them.
+func Start(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(callKey).(*call); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, callKey, &call{})
+}
+
+// Attempt returns the number of the attempt ctx is sent with. The outermost
+// balancer wrapper starts a new attempt, the ones it wraps see the same
+// number. Attempts outside of a counted call are all number 1.
+func Attempt(ctx context.Context) (context.Context, int) {
+	if a, ok := ctx.Value(attemptKey).(*attempt); ok {
+		return ctx, a.n
+	}
+	a := &attempt{n: 1}
+	if c, ok := ctx.Value(callKey).(*call); ok {
+		a.n = int(atomic.AddInt32(&c.attempts, 1))
+	}
+	return context.WithValue(ctx, attemptKey, a), a.n
+}
+
+// Select returns an endpoint of b for the attempt in ctx. When b has none, the
+// attempt is marked for NoEndpoint, whichever wrapper asked.
+func Select(ctx context.Context, b kitlb.Balancer) (endpoint.Endpoint, error) {
+	e, err := b.Endpoint()
+	if err != nil {
+		if a, ok := ctx.Value(attemptKey).(*attempt); ok {
+			a.noEndpoint = true
+		}
+	}
+	return e, err
+}
+
+// NoEndpoint reports whether the balancer had no endpoint for the attempt in
+// ctx.
+func NoEndpoint(ctx context.Context) bool {
+	a, ok := ctx.Value(attemptKey).(*attempt)
+	return ok && a.noEndpoint
+}
+
+// Attempts returns the number of attempts started so far by the call in ctx.
+func Attempts(ctx context.Context) int {
+	if c, ok := ctx.Value(callKey).(*call); ok {
+		return int(atomic.LoadInt32(&c.attempts))
+	}
+	return 0
+}
@@ -0,0 +1,203 @@
+// Package respwriter provides an http.ResponseWriter wrapper that records the
+// status code, response size and time to first byte of a response.
+package respwriter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Writer wraps an http.ResponseWriter and records what the handler wrote.
+//
+// Writer implements io.ReaderFrom whatever it wraps, falling back to io.Copy.
+// Unwrap makes the wrapped writer reachable by http.ResponseController.
+type Writer struct {
+	w           http.ResponseWriter
+	start       time.Time
+	firstByte   time.Time
+	status      int
+	size        int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// Wrap returns a Writer recording the response written to w, and the
+// http.ResponseWriter to hand to the handler in place of w. The latter
+// implements http.Flusher, http.Hijacker and http.Pusher only when w does, so
+// that handlers probing for them, directly or through http.ResponseController,
+// see what w supports.
+func Wrap(w http.ResponseWriter) (*Writer, http.ResponseWriter) {
+	wr := &Writer{w: w, start: time.Now()}
+	_, canFlush := w.(http.Flusher)
+	_, canHijack := w.(http.Hijacker)
+	_, canPush := w.(http.Pusher)
+	f, h, p := flusher{wr}, hijacker{wr}, pusher{wr}
+	switch {
+	case canFlush && canHijack && canPush:
+		return wr, struct {
+			*Writer
+			flusher
+			hijacker
+			pusher
+		}{wr, f, h, p}
+	case canFlush && canHijack:
+		return wr, struct {
+			*Writer
+			flusher
+			hijacker
+		}{wr, f, h}
+	case canFlush && canPush:
+		return wr, struct {
+			*Writer
+			flusher
+			pusher
+		}{wr, f, p}
+	case canHijack && canPush:
+		return wr, struct {
+			*Writer
+			hijacker
+			pusher
+		}{wr, h, p}
+	case canFlush:
+		return wr, struct {
+			*Writer
+			flusher
+		}{wr, f}
+	case canHijack:
+		return wr, struct {
+			*Writer
+			hijacker
+		}{wr, h}
+	case canPush:
+		return wr, struct {
+			*Writer
+			pusher
+		}{wr, p}
+	}
+	return wr, wr
+}
+
+// Status returns the status code sent to the client. A handler that never
+// calls WriteHeader or Write gets the implicit 200 of net/http.
+func (w *Writer) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Size returns the number of response body bytes written.
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Written reports whether the response header has been sent.
+func (w *Writer) Written() bool {
+	return w.wroteHeader
+}
+
+// Hijacked reports whether the connection was taken over by the handler.
+func (w *Writer) Hijacked() bool {
+	return w.hijacked
+}
+
+// TimeToFirstByte returns the time between Wrap and the response header being
+// sent, or zero if nothing was sent yet.
+func (w *Writer) TimeToFirstByte() time.Duration {
+	if w.firstByte.IsZero() {
+		return 0
+	}
+	return w.firstByte.Sub(w.start)
+}
+
+func (w *Writer) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *Writer) WriteHeader(code int) {
+	if w.wroteHeader {
+		w.w.WriteHeader(code)
+		return
+	}
+	if w.firstByte.IsZero() {
+		w.firstByte = time.Now()
+	}
+	// informational responses may precede the final one, except for 101 which
+	// switches protocols and ends the HTTP exchange.
+	if code < 100 || code > 199 || code == http.StatusSwitchingProtocols {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.w.WriteHeader(code)
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.w.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// hide ReadFrom from io.Copy so it doesn't call back into us
+		n, err = io.Copy(struct{ io.Writer }{w.w}, r)
+	}
+	w.size += n
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, see http.ResponseController.
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.w
+}
+
+// flusher forwards http.Flusher to a Writer wrapping one. FlushError is what
+// http.ResponseController calls, it keeps the error of the wrapped writer.
+type flusher struct{ w *Writer }
+
+func (f flusher) Flush() {
+	_ = f.FlushError()
+}
+
+func (f flusher) FlushError() error {
+	w := f.w
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if fe, ok := w.w.(interface{ FlushError() error }); ok {
+		return fe.FlushError()
+	}
+	w.w.(http.Flusher).Flush()
+	return nil
+}
+
+// hijacker forwards http.Hijacker to a Writer wrapping one.
+type hijacker struct{ w *Writer }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.w.(http.Hijacker).Hijack()
+	if err == nil {
+		h.w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// pusher forwards http.Pusher to a Writer wrapping one.
+type pusher struct{ w *Writer }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.w.w.(http.Pusher).Push(target, opts)
+}
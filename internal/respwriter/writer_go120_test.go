@@ -0,0 +1,62 @@
+//go:build go1.20
+
+package respwriter_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nnnewb/otelkit/internal/respwriter"
+)
+
+type flushErrorWriter struct {
+	*httptest.ResponseRecorder
+	err error
+}
+
+func (w flushErrorWriter) FlushError() error {
+	return w.err
+}
+
+func TestResponseControllerFlush(t *testing.T) {
+	errFlush := errors.New("connection gone")
+	for _, tt := range []struct {
+		name string
+		w    http.ResponseWriter
+		want error
+	}{
+		{"supported", httptest.NewRecorder(), nil},
+		{"unsupported", plainWriter{httptest.NewRecorder()}, http.ErrNotSupported},
+		{"flush error", flushErrorWriter{httptest.NewRecorder(), errFlush}, errFlush},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, w := respwriter.Wrap(tt.w)
+			if err := http.NewResponseController(w).Flush(); !errors.Is(err, tt.want) {
+				t.Errorf("Flush() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseControllerUnwrap(t *testing.T) {
+	// Writer doesn't forward deadlines, the controller reaches the server's
+	// writer through Unwrap
+	errc := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, w = respwriter.Wrap(w)
+		errc <- http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("SetWriteDeadline() = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package respwriter_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nnnewb/otelkit/internal/respwriter"
+)
+
+// plainWriter only has the methods of http.ResponseWriter.
+type plainWriter struct{ http.ResponseWriter }
+
+func TestStatusAndSize(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wr, w := respwriter.Wrap(rec)
+	if wr.Written() || wr.Status() != http.StatusOK || wr.TimeToFirstByte() != 0 {
+		t.Errorf("fresh writer: written %v, status %d, ttfb %v", wr.Written(), wr.Status(), wr.TimeToFirstByte())
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	io.WriteString(w, "not ")
+	io.WriteString(w, "found")
+
+	if !wr.Written() || wr.Status() != http.StatusNotFound || wr.Size() != 9 {
+		t.Errorf("written %v, status %d, size %d; want true, 404, 9", wr.Written(), wr.Status(), wr.Size())
+	}
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "not found" {
+		t.Errorf("recorded %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestImplicitStatus(t *testing.T) {
+	wr, w := respwriter.Wrap(httptest.NewRecorder())
+	io.WriteString(w, "ok")
+	// a second WriteHeader is passed on for net/http to complain about
+	w.WriteHeader(http.StatusTeapot)
+
+	if !wr.Written() || wr.Status() != http.StatusOK || wr.Size() != 2 {
+		t.Errorf("written %v, status %d, size %d; want true, 200, 2", wr.Written(), wr.Status(), wr.Size())
+	}
+}
+
+func TestInformational(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		codes   []int
+		status  int
+		written bool
+	}{
+		{"early hints", []int{http.StatusEarlyHints}, http.StatusOK, false},
+		{"early hints then final", []int{http.StatusEarlyHints, http.StatusCreated}, http.StatusCreated, true},
+		{"switching protocols", []int{http.StatusSwitchingProtocols}, http.StatusSwitchingProtocols, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			wr, w := respwriter.Wrap(httptest.NewRecorder())
+			for _, code := range tt.codes {
+				w.WriteHeader(code)
+			}
+			if wr.Status() != tt.status || wr.Written() != tt.written {
+				t.Errorf("status %d, written %v; want %d, %v", wr.Status(), wr.Written(), tt.status, tt.written)
+			}
+			if wr.TimeToFirstByte() == 0 {
+				t.Error("time to first byte not recorded")
+			}
+		})
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		w    http.ResponseWriter
+	}{
+		{"fallback", httptest.NewRecorder()},
+		{"forwarded", readerFromWriter{httptest.NewRecorder()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			wr, w := respwriter.Wrap(tt.w)
+			n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+			if err != nil || n != 5 {
+				t.Fatalf("ReadFrom = %d, %v", n, err)
+			}
+			if !wr.Written() || wr.Status() != http.StatusOK || wr.Size() != 5 {
+				t.Errorf("written %v, status %d, size %d; want true, 200, 5", wr.Written(), wr.Status(), wr.Size())
+			}
+		})
+	}
+}
+
+type readerFromWriter struct{ *httptest.ResponseRecorder }
+
+func (w readerFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestInterfaces(t *testing.T) {
+	for _, tt := range []struct {
+		name                      string
+		w                         http.ResponseWriter
+		flusher, hijacker, pusher bool
+	}{
+		{"plain", plainWriter{httptest.NewRecorder()}, false, false, false},
+		{"recorder", httptest.NewRecorder(), true, false, false},
+		{"http1", http1Writer{httptest.NewRecorder()}, true, true, false},
+		{"http2", http2Writer{httptest.NewRecorder()}, true, false, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, w := respwriter.Wrap(tt.w)
+			if _, ok := w.(http.Flusher); ok != tt.flusher {
+				t.Errorf("Flusher = %v, want %v", ok, tt.flusher)
+			}
+			if _, ok := w.(http.Hijacker); ok != tt.hijacker {
+				t.Errorf("Hijacker = %v, want %v", ok, tt.hijacker)
+			}
+			if _, ok := w.(http.Pusher); ok != tt.pusher {
+				t.Errorf("Pusher = %v, want %v", ok, tt.pusher)
+			}
+			if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != tt.w {
+				t.Error("Unwrap doesn't return the wrapped writer")
+			}
+		})
+	}
+}
+
+type http1Writer struct{ *httptest.ResponseRecorder }
+
+func (http1Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+type http2Writer struct{ *httptest.ResponseRecorder }
+
+func (http2Writer) Push(string, *http.PushOptions) error {
+	return http.ErrNotSupported
+}
+
+func TestFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wr, w := respwriter.Wrap(rec)
+	w.(http.Flusher).Flush()
+
+	if !wr.Written() || wr.Status() != http.StatusOK || !rec.Flushed {
+		t.Errorf("written %v, status %d, flushed %v", wr.Written(), wr.Status(), rec.Flushed)
+	}
+}
+
+func TestHijack(t *testing.T) {
+	hijacked := make(chan bool, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wr, w := respwriter.Wrap(w)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			hijacked <- false
+			return
+		}
+		conn.Close()
+		hijacked <- wr.Hijacked()
+	}))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL); err == nil {
+		resp.Body.Close()
+	}
+	if !<-hijacked {
+		t.Error("Hijacked() = false after a successful Hijack")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !go1.22
+
+package route
+
+import "net/http"
+
+// Pattern always returns an empty string, http.Request carries the matched
+// pattern since Go 1.22 only.
+func Pattern(_ *http.Request) string {
+	return ""
+}
+
+// Propagate does nothing, see Pattern.
+func Propagate(_, _ *http.Request) {}
@@ -0,0 +1,20 @@
+//go:build go1.22
+
+package route
+
+import "net/http"
+
+// Pattern returns the route template of the http.ServeMux pattern that matched
+// req, or an empty string if req was not routed by a ServeMux yet.
+func Pattern(req *http.Request) string {
+	return FromPattern(req.Pattern)
+}
+
+// Propagate copies the pattern a ServeMux matched for src, a copy of dst made
+// with WithContext, back onto dst, so middlewares wrapping the one that made
+// the copy learn the route too.
+func Propagate(dst, src *http.Request) {
+	if dst != src && dst.Pattern == "" {
+		dst.Pattern = src.Pattern
+	}
+}
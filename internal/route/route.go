@@ -0,0 +1,14 @@
+// Package route extracts the route template a request was matched against.
+package route
+
+import "strings"
+
+// FromPattern returns the path part of an http.ServeMux pattern, dropping the
+// optional method and host, "GET example.com/users/{id}" becomes
+// "/users/{id}".
+func FromPattern(pattern string) string {
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
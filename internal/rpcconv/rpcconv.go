@@ -0,0 +1,120 @@
+// Package rpcconv maps gRPC calls to the attributes of the OpenTelemetry RPC
+// semantic conventions.
+package rpcconv
+
+import (
+	"context"
+	"strings"
+
+	kgrpc "github.com/go-kit/kit/transport/grpc"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StatusCodeKey is the gRPC status code of a call.
+const StatusCodeKey = attribute.Key("rpc.grpc.status_code")
+
+// FullMethod returns the "/package.Service/Method" name of the call in ctx.
+// go-kit clients always set it, servers only when kgrpc.Interceptor is
+// installed, otherwise the grpc server stream is asked.
+func FullMethod(ctx context.Context) string {
+	if method, ok := ctx.Value(kgrpc.ContextKeyRequestMethod).(string); ok {
+		return method
+	}
+	method, _ := grpc.Method(ctx)
+	return method
+}
+
+// ParseFullMethod splits "/package.Service/Method" into its service and
+// method names.
+func ParseFullMethod(fullMethod string) (service, method string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// SpanName returns the span name of fullMethod, "package.Service/Method".
+func SpanName(fullMethod string) string {
+	if fullMethod == "" {
+		return "grpc"
+	}
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// Attributes returns the rpc.system, rpc.service and rpc.method attributes of
+// fullMethod.
+func Attributes(fullMethod string) []attribute.KeyValue {
+	service, method := ParseFullMethod(fullMethod)
+	attrs := []attribute.KeyValue{attribute.String("rpc.system", "grpc")}
+	if service != "" {
+		attrs = append(attrs, attribute.String("rpc.service", service))
+	}
+	if method != "" {
+		attrs = append(attrs, attribute.String("rpc.method", method))
+	}
+	return attrs
+}
+
+// Code returns the gRPC status code of err, codes.OK for nil.
+func Code(err error) codes.Code {
+	return status.Code(err)
+}
+
+// ServerStatus is the span status of a server call answered with code. Only
+// codes pointing at a server fault are errors, the others are the client's.
+func ServerStatus(code codes.Code, err error) (otelcodes.Code, string) {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return otelcodes.Error, message(code, err)
+	}
+	return otelcodes.Unset, ""
+}
+
+// ClientStatus is the span status of a client call answered with code, any
+// code but OK is an error.
+func ClientStatus(code codes.Code, err error) (otelcodes.Code, string) {
+	if code != codes.OK {
+		return otelcodes.Error, message(code, err)
+	}
+	return otelcodes.Unset, ""
+}
+
+func message(code codes.Code, err error) string {
+	if s, ok := status.FromError(err); ok && s.Message() != "" {
+		return s.Message()
+	}
+	return code.String()
+}
+
+// MetadataCarrier adapts metadata.MD to propagation.TextMapCarrier.
+type MetadataCarrier metadata.MD
+
+// Get returns the first value of key.
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set replaces the values of key with value.
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys lists the keys of the carrier.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,76 @@
+package otelkit
+
+import (
+	"net/http"
+
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MetricAttributesFunc returns extra attributes recorded with the request
+// metrics of req. Keep the values bounded, every distinct value creates a new
+// time series.
+type MetricAttributesFunc func(req *http.Request) []attribute.KeyValue
+
+// WithMetricAttributesFunc adds the attributes returned by fn to every request
+// metric.
+func WithMetricAttributesFunc(fn MetricAttributesFunc) Option {
+	return func(cfg *Config) {
+		cfg.MetricAttributesFunc = fn
+	}
+}
+
+// WithLegacyMetrics keeps emitting the request-count, request-duration-milli,
+// response-size-bytes and, from net/http, time-to-first-byte-milli instruments
+// of earlier releases next to the semantic convention ones, so dashboards can
+// be migrated gradually.
+func WithLegacyMetrics() Option {
+	return func(cfg *Config) {
+		cfg.LegacyMetrics = true
+	}
+}
+
+// MetricAttributes returns the attributes of a request metric following the
+// OpenTelemetry HTTP semantic conventions: method, route template, status code
+// and scheme, plus those given by WithAttributes and the MetricAttributesFunc.
+// Requests matching no route share the "_OTHER" route so unmatched paths don't
+// create new series. status is left out when zero.
+func (c *Config) MetricAttributes(req *http.Request, route string, status int) []attribute.KeyValue {
+	if route == "" {
+		route = c.OperationName
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", httpconv.Method(req)),
+		attribute.String("http.route", httpconv.Route(route)),
+		attribute.String("url.scheme", httpconv.Scheme(req)),
+	}
+	if status != 0 {
+		attrs = append(attrs, attribute.Int("http.response.status_code", status))
+	}
+	attrs = append(attrs, c.Attributes...)
+	if c.MetricAttributesFunc != nil {
+		attrs = append(attrs, c.MetricAttributesFunc(req)...)
+	}
+	return attrs
+}
+
+// LegacyMetricAttributes is MetricAttributes with the attribute names used by
+// the legacy instruments, see WithLegacyMetrics.
+func (c *Config) LegacyMetricAttributes(req *http.Request, route string, status int) []attribute.KeyValue {
+	if route == "" {
+		route = c.OperationName
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("method", httpconv.Method(req)),
+		attribute.String("route", httpconv.Route(route)),
+		attribute.String("scheme", httpconv.Scheme(req)),
+	}
+	if status != 0 {
+		attrs = append(attrs, attribute.Int("status_code", status))
+	}
+	attrs = append(attrs, c.Attributes...)
+	if c.MetricAttributesFunc != nil {
+		attrs = append(attrs, c.MetricAttributesFunc(req)...)
+	}
+	return attrs
+}
@@ -0,0 +1,14 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MeasureHandleFunc measures the requests handled by gin with meter.
+//
+// Deprecated: use NewMeasureHandleFunc(otelkit.WithMeter(meter)).
+func MeasureHandleFunc(meter metric.Meter) gin.HandlerFunc {
+	return NewMeasureHandleFunc(otelkit.WithMeter(meter))
+}
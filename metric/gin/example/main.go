@@ -1,37 +1,45 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gin2 "github.com/nnnewb/otelkit/metric/gin"
+	"github.com/nnnewb/otelkit/setup"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/otel/exporters/prometheus"
-	"go.opentelemetry.io/otel/sdk/metric"
 )
 
+const service = "gin-app"
+
 func main() {
-	promExporter, err := prometheus.New()
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
+		if err != nil {
+			log.Printf("shutdown returned error %+v", err)
+		}
+	}()
 
-	provider := metric.NewMeterProvider(metric.WithReader(promExporter))
-	meter := provider.Meter("http-example")
-
-	// serving /metrics endpoint
+	// scrape /metrics when run with OTEL_METRICS_EXPORTER=prometheus
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
-		log.Println("prometheus scrap endpoint start serving at http://192.168.56.1:23333/metrics")
-		err := http.ListenAndServe("192.168.56.1:23333", http.DefaultServeMux)
+		log.Println("prometheus scrap endpoint start serving at http://127.0.0.1:23333/metrics")
+		err := http.ListenAndServe("127.0.0.1:23333", http.DefaultServeMux)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}()
 
 	app := gin.New()
-	app.Use(gin2.MeasureHandleFunc(meter))
+	app.Use(gin2.NewMeasureHandleFunc())
 	app.Handle(http.MethodGet, "/hello", func(c *gin.Context) {
 		c.JSON(200, "Hello world")
 	})
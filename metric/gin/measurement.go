@@ -1,50 +1,76 @@
 package gin
 
 import (
-	"time"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.opentelemetry.io/otel/attribute"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/ginconv"
+	"github.com/nnnewb/otelkit/internal/httpmetric"
 	"go.opentelemetry.io/otel/metric"
 )
 
-func MeasureHandleFunc(meter metric.Meter) gin.HandlerFunc {
-	// throughput
-	requestCounter, err := meter.Int64Counter("request-count")
-	if err != nil {
-		panic(err)
-	}
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/gin"
+
+// NewMeasureHandleFunc returns a gin middleware recording the HTTP server
+// metrics of every request, keyed by its route template.
+func NewMeasureHandleFunc(opts ...otelkit.Option) gin.HandlerFunc {
+	cfg := otelkit.NewConfig(opts...)
+	meter := cfg.Meter(ScopeName)
+	server := httpmetric.NewServer(meter, cfg)
 
-	// request duration
-	durationHistogram, err := meter.Int64Histogram("request-duration-milli")
+	// errors pushed by handlers with c.Error
+	errorCounter, err := meter.Int64Counter(
+		"gin.server.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Number of errors recorded in gin.Context.Errors."))
 	if err != nil {
 		panic(err)
 	}
 
 	return func(c *gin.Context) {
-		req := c.Request
-
-		requestCounter.Add(
-			req.Context(), 1,
-			metric.WithAttributes(
-				attribute.String("url", req.URL.String()),
-				attribute.String("method", req.Method),
-				attribute.String("peer", req.RemoteAddr),
-			),
-		)
-		start := time.Now()
+		if !cfg.Instrumented(c.Request) {
+			c.Next()
+			return
+		}
+
+		m := server.Begin(c.Request)
+		panicked := true
 		defer func() {
-			durationHistogram.Record(
-				req.Context(),
-				time.Since(start).Milliseconds(),
-				metric.WithAttributes(
-					attribute.String("url", req.URL.String()),
-					attribute.String("method", req.Method),
-					attribute.String("peer", req.RemoteAddr),
-				),
-			)
+			var recovered interface{}
+			if panicked && cfg.Recovery != otelkit.RecoveryDisabled {
+				recovered = recover()
+			}
+			repanic := recovered != nil && (cfg.Recovery == otelkit.RecoveryRepanic || recovered == http.ErrAbortHandler)
+			if recovered != nil && !repanic && !c.Writer.Written() {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+
+			// a panicking request is measured as a 500 whatever got written,
+			// even when it is not recovered
+			status := c.Writer.Status()
+			if panicked {
+				status = http.StatusInternalServerError
+			}
+			m.End(c.FullPath(), status, int64(c.Writer.Size()))
+
+			if len(c.Errors) > 0 {
+				attrs := cfg.MetricAttributes(c.Request, c.FullPath(), status)
+				for _, err := range c.Errors {
+					errorCounter.Add(detach.Context(c.Request.Context()), 1, metric.WithAttributes(
+						append(attrs, ginconv.ErrorTypeKey.String(ginconv.ErrorType(err)))...))
+				}
+			}
+
+			if repanic {
+				panic(recovered)
+			}
 		}()
 
 		c.Next()
+		panicked = false
 	}
 }
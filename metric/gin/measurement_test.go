@@ -0,0 +1,103 @@
+package gin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	metricgin "github.com/nnnewb/otelkit/metric/gin"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMeasureHandleFunc(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(metricgin.NewMeasureHandleFunc(h.Options()...))
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "user %s", c.Param("id"))
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 2,
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.response.status_code", http.StatusOK))
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.String("http.route", "_OTHER"),
+		attribute.Int("http.response.status_code", http.StatusNotFound))
+	h.RequireSum(t, "http.server.active_requests", 0)
+	h.RequireNoMetric(t, "gin.server.errors")
+}
+
+func TestMeasureHandleFuncErrors(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(metricgin.NewMeasureHandleFunc(h.Options()...))
+	r.POST("/users", func(c *gin.Context) {
+		_ = c.Error(errors.New("bad input")).SetType(gin.ErrorTypeBind)
+		c.Status(http.StatusBadRequest)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	h.RequireSum(t, "gin.server.errors", 1,
+		attribute.String("gin.error.type", "bind"),
+		attribute.String("http.route", "/users"),
+		attribute.Int("http.response.status_code", http.StatusBadRequest))
+}
+
+func TestMeasureHandleFuncCanceled(t *testing.T) {
+	h := otelkittest.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := gin.New()
+	r.Use(metricgin.NewMeasureHandleFunc(h.Options()...))
+	r.GET("/users", func(c *gin.Context) {
+		// the client went away
+		cancel()
+		_ = c.Error(c.Request.Context().Err())
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil).WithContext(ctx))
+
+	h.RequireSum(t, "http.server.active_requests", 0)
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusServiceUnavailable))
+	h.RequireSum(t, "gin.server.errors", 1)
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(metricgin.NewMeasureHandleFunc(h.Options()...))
+	r.GET("/users/:id", func(c *gin.Context) {
+		_ = c.Error(errors.New("no such user")).SetType(gin.ErrorTypePublic)
+		c.String(http.StatusNotFound, "not found")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	h.RequireGolden(t, "golden")
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(metricgin.MeasureHandleFunc(h.MeterProvider.Meter("legacy")))
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.String("http.route", "/users/:id"))
+}
@@ -0,0 +1,15 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MeasureHandler measures the requests served by next with meter.
+//
+// Deprecated: use NewMeasureHandler(otelkit.WithMeter(meter)).
+func MeasureHandler(meter metric.Meter) func(http.Handler) http.Handler {
+	return NewMeasureHandler(otelkit.WithMeter(meter))
+}
@@ -2,47 +2,91 @@ package http
 
 import (
 	"net/http"
-	"time"
 
-	"go.opentelemetry.io/otel/attribute"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/httpmetric"
+	"github.com/nnnewb/otelkit/internal/respwriter"
+	"github.com/nnnewb/otelkit/internal/route"
 	"go.opentelemetry.io/otel/metric"
 )
 
-func MeasureHandler(meter metric.Meter) func(http.Handler) http.Handler {
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/http"
+
+// NewMeasureHandler returns a middleware recording the HTTP server metrics of
+// the requests served by next, keyed by the ServeMux pattern the request
+// matched, or otelkit.WithOperationName.
+func NewMeasureHandler(opts ...otelkit.Option) func(http.Handler) http.Handler {
+	cfg := otelkit.NewConfig(opts...)
+	meter := cfg.Meter(ScopeName)
 	return func(next http.Handler) http.Handler {
-		// throughput
-		requestCounter, err := meter.Int64Counter("request-count")
-		if err != nil {
-			panic(err)
-		}
+		server := httpmetric.NewServer(meter, cfg)
+
+		var firstByteHistogram, responseSizeHistogram metric.Int64Histogram
+		if cfg.LegacyMetrics {
+			var err error
 
-		// request duration
-		durationHistogram, err := meter.Int64Histogram("request-duration-milli")
-		if err != nil {
-			panic(err)
+			// time until the response header is sent, only known to net/http
+			firstByteHistogram, err = meter.Int64Histogram("time-to-first-byte-milli")
+			if err != nil {
+				panic(err)
+			}
+
+			// response body size, superseded by http.server.response.body.size
+			responseSizeHistogram, err = meter.Int64Histogram("response-size-bytes")
+			if err != nil {
+				panic(err)
+			}
 		}
 
-		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
-			requestCounter.Add(
-				req.Context(), 1,
-				metric.WithAttributes(
-					attribute.String("url", req.URL.String()),
-					attribute.String("method", req.Method),
-					attribute.String("peer", req.RemoteAddr),
-				),
-			)
-
-			start := time.Now()
-			next.ServeHTTP(wr, req)
-			durationHistogram.Record(
-				req.Context(),
-				time.Since(start).Milliseconds(),
-				metric.WithAttributes(
-					attribute.String("url", req.URL.String()),
-					attribute.String("method", req.Method),
-					attribute.String("peer", req.RemoteAddr),
-				),
-			)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !cfg.Instrumented(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			m := server.Begin(req)
+			wr, w := respwriter.Wrap(w)
+			panicked := true
+			defer func() {
+				var recovered interface{}
+				if panicked && cfg.Recovery != otelkit.RecoveryDisabled {
+					recovered = recover()
+				}
+				repanic := recovered != nil && (cfg.Recovery == otelkit.RecoveryRepanic || recovered == http.ErrAbortHandler)
+				if recovered != nil && !repanic && !wr.Written() {
+					wr.WriteHeader(http.StatusInternalServerError)
+				}
+
+				// a panicking request is measured as a 500 whatever got written,
+				// even when it is not recovered
+				status := wr.Status()
+				if panicked {
+					status = http.StatusInternalServerError
+				}
+
+				// an http.ServeMux routing req sets the matched pattern on it
+				r := route.Pattern(req)
+				m.End(r, status, wr.Size())
+
+				if cfg.LegacyMetrics {
+					ctx := detach.Context(req.Context())
+					attrs := metric.WithAttributes(cfg.LegacyMetricAttributes(req, r, status)...)
+					if wr.Written() {
+						firstByteHistogram.Record(ctx, wr.TimeToFirstByte().Milliseconds(), attrs)
+					}
+					responseSizeHistogram.Record(ctx, wr.Size(), attrs)
+				}
+
+				if repanic {
+					panic(recovered)
+				}
+			}()
+
+			next.ServeHTTP(w, req)
+			panicked = false
 		})
 	}
 }
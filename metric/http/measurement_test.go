@@ -0,0 +1,175 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	metrichttp "github.com/nnnewb/otelkit/metric/http"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestMeasureHandler(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := metrichttp.NewMeasureHandler(h.Options(otelkit.WithOperationName("/users"))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("name=x")))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", http.MethodPost),
+		attribute.String("http.route", "/users"),
+		attribute.String("url.scheme", "http"),
+		attribute.Int("http.response.status_code", http.StatusNotFound),
+	}
+	h.RequireHistogram(t, "http.server.request.duration", 2, attrs...)
+	h.RequireHistogram(t, "http.server.request.body.size", 2, attrs...)
+	h.RequireHistogram(t, "http.server.response.body.size", 2, attrs...)
+	h.RequireSum(t, "http.server.active_requests", 0,
+		attribute.String("http.request.method", http.MethodPost))
+	h.RequireNoMetric(t, "request-count")
+	h.RequireNoMetric(t, "response-size-bytes")
+	h.RequireNoMetric(t, "time-to-first-byte-milli")
+}
+
+func TestMeasureHandlerCanceled(t *testing.T) {
+	h := otelkittest.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := metrichttp.NewMeasureHandler(h.Options()...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the client went away
+			cancel()
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	h.RequireSum(t, "http.server.active_requests", 0)
+	h.RequireHistogram(t, "http.server.request.duration", 1)
+	h.RequireHistogram(t, "http.server.response.body.size", 1)
+}
+
+func TestMeasureHandlerPanic(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := metrichttp.NewMeasureHandler(h.Options(otelkit.WithPanicRecovery(otelkit.RecoveryRespond))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusInternalServerError))
+}
+
+func TestMeasureHandlerLegacy(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := metrichttp.NewMeasureHandler(h.Options(otelkit.WithLegacyMetrics())...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireSum(t, "request-count", 1,
+		attribute.String("method", http.MethodGet),
+		attribute.Int("status_code", http.StatusOK))
+	h.RequireHistogram(t, "response-size-bytes", 1,
+		attribute.String("method", http.MethodGet))
+	h.RequireHistogram(t, "time-to-first-byte-milli", 1,
+		attribute.String("method", http.MethodGet))
+	h.RequireHistogram(t, "http.server.request.duration", 1)
+}
+
+func TestMeasureTransport(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: metrichttp.MeasureTransport(h.Options()...)(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.String("server.address", "127.0.0.1"),
+		attribute.Int("http.response.status_code", http.StatusBadGateway),
+	}
+	h.RequireHistogram(t, "http.client.request.duration", 1, attrs...)
+	h.RequireSum(t, "http.client.request.errors", 1, attrs...)
+	h.RequireSum(t, "http.client.active_requests", 0)
+}
+
+func TestMetricAttributesFunc(t *testing.T) {
+	h := otelkittest.New(t)
+	tenant := otelkit.WithMetricAttributesFunc(func(req *http.Request) []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.String("tenant", req.Header.Get("X-Tenant"))}
+	})
+	srv := httptest.NewServer(metrichttp.NewMeasureHandler(h.Options(tenant, otelkit.WithLegacyMetrics())...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: metrichttp.MeasureTransport(h.Options(tenant)...)(nil)}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	acme := attribute.String("tenant", "acme")
+	h.RequireHistogram(t, "http.server.request.duration", 1, acme)
+	h.RequireHistogram(t, "http.server.response.body.size", 1, acme)
+	h.RequireSum(t, "request-count", 1, acme)
+	h.RequireHistogram(t, "http.client.request.duration", 1, acme)
+	h.RequireSum(t, "http.client.request.errors", 1, acme)
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(metrichttp.NewMeasureHandler(h.Options(otelkit.WithOperationName("/users"))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("[]"))
+		})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: metrichttp.MeasureTransport(h.Options()...)(nil)}
+	resp, err := client.Post(srv.URL+"/users", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// the connection is only reused when the test runs more than once
+	h.RequireGolden(t, "golden", otelkittest.ScrubAttributes("http.connection.reused"))
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := metrichttp.MeasureHandler(h.MeterProvider.Meter("legacy"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusOK))
+}
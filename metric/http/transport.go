@@ -0,0 +1,103 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/httpmetric"
+)
+
+// MeasureTransport wraps an http.RoundTripper so that every outbound request
+// is measured: duration, in-flight requests, status, body sizes and
+// connection reuse, keyed by destination host. A request is done once its
+// response body is closed or fully read, or immediately when the round trip
+// fails.
+func MeasureTransport(opts ...otelkit.Option) func(next http.RoundTripper) http.RoundTripper {
+	cfg := otelkit.NewConfig(opts...)
+	client := httpmetric.NewClient(cfg.Meter(ScopeName), cfg)
+	return func(next http.RoundTripper) http.RoundTripper {
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return &transport{next: next, cfg: cfg, client: client}
+	}
+}
+
+type transport struct {
+	next   http.RoundTripper
+	cfg    *otelkit.Config
+	client *httpmetric.Client
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cfg.Instrumented(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	m := t.client.Begin(req.Context(), req)
+	resp, err := t.next.RoundTrip(req.WithContext(m.ClientTrace(req.Context())))
+	if err != nil {
+		m.End(err)
+		return resp, err
+	}
+
+	// look for a writable body before Response wraps it
+	rw, writable := resp.Body.(io.ReadWriteCloser)
+	m.Response(resp)
+	if resp.Body == nil || resp.Body == http.NoBody {
+		m.End(nil)
+		return resp, nil
+	}
+
+	body := &measuredBody{ReadCloser: resp.Body, m: m}
+	if writable {
+		// protocol upgrades (101 Switching Protocols) hand out a writable body,
+		// keep it writable.
+		resp.Body = &measuredReadWriteBody{measuredBody: body, w: rw}
+	} else {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+// measuredBody ends the measurement once the body is drained or closed.
+type measuredBody struct {
+	io.ReadCloser
+	m    *httpmetric.ClientMeasurement
+	once sync.Once
+}
+
+func (b *measuredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch err {
+	case nil:
+	case io.EOF:
+		b.end(nil)
+	default:
+		b.end(err)
+	}
+	return n, err
+}
+
+func (b *measuredBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end(nil)
+	return err
+}
+
+func (b *measuredBody) end(err error) {
+	b.once.Do(func() {
+		b.m.End(err)
+	})
+}
+
+type measuredReadWriteBody struct {
+	*measuredBody
+	w io.Writer
+}
+
+func (b *measuredReadWriteBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
@@ -0,0 +1,21 @@
+package kit
+
+import (
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MeasureServerBefore starts measuring requests with meter.
+//
+// Deprecated: use NewMeasureServerBefore(otelkit.WithMeter(meter)).
+func MeasureServerBefore(meter metric.Meter) khttp.ServerOption {
+	return NewMeasureServerBefore(otelkit.WithMeter(meter))
+}
+
+// MeasureServerFinalizer records the requests measured by MeasureServerBefore.
+//
+// Deprecated: use NewMeasureServerFinalizer(otelkit.WithMeter(meter)).
+func MeasureServerFinalizer(meter metric.Meter) khttp.ServerOption {
+	return NewMeasureServerFinalizer(otelkit.WithMeter(meter))
+}
@@ -0,0 +1,62 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/kitphase"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MeasureEndpoint returns an endpoint.Middleware recording the duration and
+// the errors of every invocation of the endpoint named operation. Business
+// errors reported by responses implementing endpoint.Failer count as errors.
+func MeasureEndpoint(operation string, opts ...otelkit.Option) endpoint.Middleware {
+	cfg := otelkit.NewConfig(opts...)
+	meter := cfg.Meter(ScopeName)
+
+	// endpoint duration
+	durationHistogram, err := meter.Float64Histogram(
+		"gokit.endpoint.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of go-kit endpoint invocations."))
+	if err != nil {
+		panic(err)
+	}
+
+	// endpoint errors
+	errorCounter, err := meter.Int64Counter(
+		"gokit.endpoint.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Number of go-kit endpoint invocations that failed."))
+	if err != nil {
+		panic(err)
+	}
+
+	attrs := append([]attribute.KeyValue{attribute.String("gokit.endpoint", operation)}, cfg.Attributes...)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			leave := kitphase.EnterEndpoint(ctx)
+			start := time.Now()
+			response, err := next(ctx, request)
+			leave(err)
+			measureCtx := detach.Context(ctx)
+			durationHistogram.Record(measureCtx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+
+			failed := err
+			if failer, ok := response.(endpoint.Failer); ok && err == nil {
+				failed = failer.Failed()
+			}
+			if failed != nil {
+				errorCounter.Add(measureCtx, 1, metric.WithAttributes(
+					append(attrs[:len(attrs):len(attrs)], attribute.String("error.type", fmt.Sprintf("%T", failed)))...))
+			}
+			return response, err
+		}
+	}
+}
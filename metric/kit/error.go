@@ -0,0 +1,77 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/transport"
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"github.com/nnnewb/otelkit/internal/kitphase"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MeasureErrorHandler returns a transport.ErrorHandler counting the errors of
+// a go-kit server by the phase they happened in: decode, endpoint or encode.
+// Install it with khttp.ServerErrorHandler.
+//
+// Decode errors are told by a decoder wrapped by MeasureDecodeRequest or
+// tracing/kit.TraceDecodeRequest, endpoint errors by an endpoint wrapped by
+// MeasureEndpoint or tracing/kit.TraceEndpoint. Other errors are counted in
+// the unknown phase.
+func MeasureErrorHandler(opts ...otelkit.Option) transport.ErrorHandler {
+	count := errorCounter(otelkit.NewConfig(opts...))
+	return transport.ErrorHandlerFunc(count)
+}
+
+// MeasureErrorEncoder wraps next, khttp.DefaultErrorEncoder when nil, to count
+// the error it encodes like MeasureErrorHandler does. An error seen by both is
+// counted once.
+func MeasureErrorEncoder(next khttp.ErrorEncoder, opts ...otelkit.Option) khttp.ErrorEncoder {
+	if next == nil {
+		next = khttp.DefaultErrorEncoder
+	}
+	count := errorCounter(otelkit.NewConfig(opts...))
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		count(ctx, err)
+		next(ctx, err, w)
+	}
+}
+
+// MeasureDecodeRequest wraps dec so its errors are counted in the decode phase
+// by MeasureErrorHandler and MeasureErrorEncoder.
+func MeasureDecodeRequest(dec khttp.DecodeRequestFunc) khttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		done := kitphase.Decoding(ctx)
+		request, err := dec(ctx, r)
+		done(err)
+		return request, err
+	}
+}
+
+func errorCounter(cfg *otelkit.Config) func(ctx context.Context, err error) {
+	counter, err := cfg.Meter(ScopeName).Int64Counter(
+		"gokit.server.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Number of go-kit server errors by phase."))
+	if err != nil {
+		panic(err)
+	}
+
+	return func(ctx context.Context, err error) {
+		if !kitphase.First(ctx, ScopeName) {
+			return
+		}
+		attrs := []attribute.KeyValue{
+			kitphase.Key.String(kitphase.Phase(ctx)),
+			attribute.String("error.type", fmt.Sprintf("%T", err)),
+			attribute.String("http.route", httpconv.Route(cfg.OperationName)),
+		}
+		attrs = append(attrs, cfg.Attributes...)
+		counter.Add(detach.Context(ctx), 1, metric.WithAttributes(attrs...))
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
 	"github.com/nnnewb/otelkit/metric/kit"
+	"github.com/nnnewb/otelkit/setup"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/otel/exporters/prometheus"
-	"go.opentelemetry.io/otel/sdk/metric"
 )
 
 type ExampleEndpointRequest struct{}
@@ -31,31 +32,38 @@ func endpoint(ctx context.Context, req interface{}) (interface{}, error) {
 	return example(ctx, req.(*ExampleEndpointRequest))
 }
 
+const service = "kit-http-app"
+
 func main() {
-	promExporter, err := prometheus.New()
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
+		if err != nil {
+			log.Printf("shutdown returned error %+v", err)
+		}
+	}()
 
-	provider := metric.NewMeterProvider(metric.WithReader(promExporter))
-	meter := provider.Meter("http-example")
-
-	// serving /metrics endpoint
+	// scrape /metrics when run with OTEL_METRICS_EXPORTER=prometheus
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
-		log.Println("prometheus scrap endpoint start serving at http://192.168.56.1:23333/metrics")
-		err := http.ListenAndServe("192.168.56.1:23333", http.DefaultServeMux)
+		log.Println("prometheus scrap endpoint start serving at http://127.0.0.1:23333/metrics")
+		err := http.ListenAndServe("127.0.0.1:23333", http.DefaultServeMux)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}()
 
 	svr := khttp.NewServer(
-		endpoint,
+		kit.MeasureEndpoint("hello")(endpoint),
 		decodeExampleRequest,
 		khttp.EncodeJSONResponse,
-		kit.MeasureServerBefore(meter),
-		kit.MeasureServerFinalizer(meter),
+		kit.NewMeasureServerBefore(otelkit.WithOperationName("/hello")),
+		kit.NewMeasureServerFinalizer(),
 	)
 
 	http.DefaultServeMux.Handle("/hello", svr)
@@ -0,0 +1,102 @@
+// Package grpc measures go-kit gRPC transports following the OpenTelemetry RPC
+// semantic conventions. The server options need kgrpc.Interceptor, or a grpc
+// server stream in the context, to learn the called method.
+package grpc
+
+import (
+	"context"
+	"time"
+
+	kgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/rpcconv"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc/metadata"
+)
+
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/kit/grpc"
+
+type measurementKeyT struct{}
+
+var measurementKey measurementKeyT
+
+type measurement struct {
+	duration   metric.Float64Histogram
+	fullMethod string
+	start      time.Time
+}
+
+// MeasureServerBefore starts timing the call, the rpc.server.duration metric
+// is recorded by MeasureServerFinalizer.
+func MeasureServerBefore(opts ...otelkit.Option) kgrpc.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	duration, err := cfg.Meter(ScopeName).Float64Histogram(
+		"rpc.server.duration",
+		metric.WithUnit("ms"),
+		metric.WithDescription("Duration of inbound RPCs."))
+	if err != nil {
+		panic(err)
+	}
+
+	return kgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+		return context.WithValue(ctx, measurementKey, &measurement{
+			duration:   duration,
+			fullMethod: rpcconv.FullMethod(ctx),
+			start:      time.Now(),
+		})
+	})
+}
+
+// MeasureServerFinalizer records the call duration with its method and gRPC
+// status code.
+func MeasureServerFinalizer(opts ...otelkit.Option) kgrpc.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ServerFinalizer(func(ctx context.Context, err error) {
+		if m, ok := ctx.Value(measurementKey).(*measurement); ok {
+			m.end(ctx, cfg, err)
+		}
+	})
+}
+
+// MeasureClientBefore starts timing the call, the rpc.client.duration metric
+// is recorded by MeasureClientFinalizer.
+func MeasureClientBefore(opts ...otelkit.Option) kgrpc.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	duration, err := cfg.Meter(ScopeName).Float64Histogram(
+		"rpc.client.duration",
+		metric.WithUnit("ms"),
+		metric.WithDescription("Duration of outbound RPCs."))
+	if err != nil {
+		panic(err)
+	}
+
+	return kgrpc.ClientBefore(func(ctx context.Context, md *metadata.MD) context.Context {
+		return context.WithValue(ctx, measurementKey, &measurement{
+			duration:   duration,
+			fullMethod: rpcconv.FullMethod(ctx),
+			start:      time.Now(),
+		})
+	})
+}
+
+// MeasureClientFinalizer records the call duration with its method and gRPC
+// status code.
+func MeasureClientFinalizer(opts ...otelkit.Option) kgrpc.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ClientFinalizer(func(ctx context.Context, err error) {
+		if m, ok := ctx.Value(measurementKey).(*measurement); ok {
+			m.end(ctx, cfg, err)
+		}
+	})
+}
+
+func (m *measurement) end(ctx context.Context, cfg *otelkit.Config, err error) {
+	elapsed := time.Since(m.start)
+	attrs := rpcconv.Attributes(m.fullMethod)
+	attrs = append(attrs, rpcconv.StatusCodeKey.Int(int(rpcconv.Code(err))))
+	attrs = append(attrs, cfg.Attributes...)
+	m.duration.Record(detach.Context(ctx), float64(elapsed)/float64(time.Millisecond), metric.WithAttributes(attrs...))
+}
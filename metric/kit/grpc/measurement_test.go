@@ -0,0 +1,115 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	kgrpc "github.com/go-kit/kit/transport/grpc"
+	metricgrpc "github.com/nnnewb/otelkit/metric/kit/grpc"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// failures maps the service names asked for to the code the server fails
+// with.
+var failures = map[string]codes.Code{
+	"missing": codes.NotFound,
+	"broken":  codes.Internal,
+}
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	check kgrpc.Handler
+}
+
+func (s healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	_, resp, err := s.check.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*healthpb.HealthCheckResponse), nil
+}
+
+func passthrough(_ context.Context, v interface{}) (interface{}, error) { return v, nil }
+
+// dial serves the health service through go-kit over an in-process listener
+// and returns a go-kit client endpoint calling its Check method.
+func dial(t *testing.T, serverOpts []kgrpc.ServerOption, clientOpts []kgrpc.ClientOption) func(context.Context, string) error {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(kgrpc.Interceptor))
+	healthpb.RegisterHealthServer(srv, healthServer{check: kgrpc.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			if code, ok := failures[request.(*healthpb.HealthCheckRequest).Service]; ok {
+				return nil, status.Error(code, code.String())
+			}
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+		},
+		passthrough, passthrough, serverOpts...)})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	check := kgrpc.NewClient(conn, "grpc.health.v1.Health", "Check",
+		passthrough, passthrough, &healthpb.HealthCheckResponse{}, clientOpts...).Endpoint()
+	return func(ctx context.Context, service string) error {
+		_, err := check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		return err
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	h := otelkittest.New(t)
+	opts := h.Options()
+	check := dial(t, []kgrpc.ServerOption{
+		metricgrpc.MeasureServerBefore(opts...),
+		metricgrpc.MeasureServerFinalizer(opts...),
+	}, []kgrpc.ClientOption{
+		metricgrpc.MeasureClientBefore(opts...),
+		metricgrpc.MeasureClientFinalizer(opts...),
+	})
+
+	_ = check(context.Background(), "")
+	_ = check(context.Background(), "")
+	_ = check(context.Background(), "missing")
+	_ = check(context.Background(), "broken")
+
+	for _, tt := range []struct {
+		code  codes.Code
+		count uint64
+	}{
+		{codes.OK, 2},
+		{codes.NotFound, 1},
+		{codes.Internal, 1},
+	} {
+		attrs := []attribute.KeyValue{
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", "grpc.health.v1.Health"),
+			attribute.String("rpc.method", "Check"),
+			attribute.Int("rpc.grpc.status_code", int(tt.code)),
+		}
+		h.RequireHistogram(t, "rpc.server.duration", tt.count, attrs...)
+		h.RequireHistogram(t, "rpc.client.duration", tt.count, attrs...)
+	}
+
+	// the RPC semantic conventions measure durations in milliseconds, which
+	// also keeps setup.DurationView and its buckets in seconds away
+	for _, name := range []string{"rpc.server.duration", "rpc.client.duration"} {
+		if m, _ := h.Metric(t, name); m.Unit != "ms" {
+			t.Errorf("%s unit = %q, want ms", name, m.Unit)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package lb measures go-kit client-side load balancing: retries, exhausted
+// retries and the instances calls are sent to.
+//
+//	factory = lb.MeasureFactory(factory, opts...)
+//	balancer := lb.MeasureBalancer(kitlb.NewRoundRobin(endpointer), opts...)
+//	ep := lb.MeasureRetry(opts...)(kitlb.Retry(3, time.Second, balancer))
+package lb
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	kitlb "github.com/go-kit/kit/sd/lb"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/detach"
+	"github.com/nnnewb/otelkit/internal/lbconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/kit/lb"
+
+// MeasureRetry returns an endpoint.Middleware for the endpoint built by
+// lb.Retry, counting the retries of every call and the calls that exhausted
+// their retries. Attempts are only counted when the balancer is wrapped by
+// MeasureBalancer or tracing/kit/lb.TraceBalancer.
+func MeasureRetry(opts ...otelkit.Option) endpoint.Middleware {
+	cfg := otelkit.NewConfig(opts...)
+	meter := cfg.Meter(ScopeName)
+
+	// attempts after the first one
+	retryCounter, err := meter.Int64Counter(
+		"gokit.lb.retries",
+		metric.WithUnit("{retry}"),
+		metric.WithDescription("Number of retried attempts of load balanced calls."))
+	if err != nil {
+		panic(err)
+	}
+
+	// calls failing after their last attempt
+	exhaustedCounter, err := meter.Int64Counter(
+		"gokit.lb.exhausted",
+		metric.WithUnit("{call}"),
+		metric.WithDescription("Number of load balanced calls that exhausted their retries."))
+	if err != nil {
+		panic(err)
+	}
+
+	attrs := metric.WithAttributes(attributes(cfg)...)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx = lbconv.Start(ctx)
+			response, err := next(ctx, request)
+			if n := lbconv.Attempts(ctx); n > 1 {
+				retryCounter.Add(detach.Context(ctx), int64(n-1), attrs)
+			}
+			var retryErr kitlb.RetryError
+			if errors.As(err, &retryErr) {
+				exhaustedCounter.Add(detach.Context(ctx), 1, attrs)
+			}
+			return response, err
+		}
+	}
+}
+
+// MeasureBalancer wraps b to number the attempts of a call and count the
+// times b had no endpoint to hand out.
+//
+// The endpoint is only selected from b when the attempt runs, so that an
+// attempt finding no endpoint is counted too. Its error is returned by the
+// endpoint rather than by Endpoint.
+func MeasureBalancer(b kitlb.Balancer, opts ...otelkit.Option) kitlb.Balancer {
+	cfg := otelkit.NewConfig(opts...)
+	failures, err := cfg.Meter(ScopeName).Int64Counter(
+		"gokit.lb.selection.errors",
+		metric.WithUnit("{error}"),
+		metric.WithDescription("Number of times the balancer could not select an endpoint."))
+	if err != nil {
+		panic(err)
+	}
+	return &balancer{next: b, failures: failures, attrs: attributes(cfg)}
+}
+
+type balancer struct {
+	next     kitlb.Balancer
+	failures metric.Int64Counter
+	attrs    []attribute.KeyValue
+}
+
+func (b *balancer) Endpoint() (endpoint.Endpoint, error) {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		ctx, _ = lbconv.Attempt(ctx)
+		e, err := lbconv.Select(ctx, b.next)
+		if err == nil {
+			response, err = e(ctx, request)
+		}
+		// a TraceBalancer wrapped by b selects on its behalf
+		if lbconv.NoEndpoint(ctx) {
+			b.failures.Add(detach.Context(ctx), 1, metric.WithAttributes(b.attrs...))
+		}
+		return response, err
+	}, nil
+}
+
+// MeasureFactory wraps factory so every call sent to one of its endpoints
+// counts as a selection of its instance.
+func MeasureFactory(factory sd.Factory, opts ...otelkit.Option) sd.Factory {
+	cfg := otelkit.NewConfig(opts...)
+	selections, err := cfg.Meter(ScopeName).Int64Counter(
+		"gokit.lb.selections",
+		metric.WithUnit("{selection}"),
+		metric.WithDescription("Number of calls sent to each instance."))
+	if err != nil {
+		panic(err)
+	}
+	base := attributes(cfg)
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		e, closer, err := factory(instance)
+		if err != nil {
+			return e, closer, err
+		}
+		attrs := metric.WithAttributes(append(base[:len(base):len(base)], lbconv.InstanceKey.String(instance))...)
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			selections.Add(detach.Context(ctx), 1, attrs)
+			return e(ctx, request)
+		}, closer, nil
+	}
+}
+
+// attributes names the balanced endpoint by otelkit.WithOperationName.
+func attributes(cfg *otelkit.Config) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if cfg.OperationName != "" {
+		attrs = append(attrs, attribute.String("gokit.endpoint", cfg.OperationName))
+	}
+	return append(attrs, cfg.Attributes...)
+}
@@ -0,0 +1,98 @@
+package lb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kitlb "github.com/go-kit/kit/sd/lb"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/metric/kit/lb"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracelb "github.com/nnnewb/otelkit/tracing/kit/lb"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var errDown = errors.New("instance down")
+
+// script is a balancer handing out its endpoints in order, a nil one stands
+// for no endpoint.
+type script struct {
+	mu        sync.Mutex
+	endpoints []endpoint.Endpoint
+}
+
+func (s *script) Endpoint() (endpoint.Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.endpoints) == 0 {
+		return nil, kitlb.ErrNoEndpoints
+	}
+	e := s.endpoints[0]
+	s.endpoints = s.endpoints[1:]
+	if e == nil {
+		return nil, kitlb.ErrNoEndpoints
+	}
+	return e, nil
+}
+
+func TestRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		balance func(kitlb.Balancer, *otelkittest.Harness) kitlb.Balancer
+	}{
+		{"measured", func(b kitlb.Balancer, h *otelkittest.Harness) kitlb.Balancer {
+			return lb.MeasureBalancer(b, h.Options()...)
+		}},
+		// the traced balancer selects the endpoint on behalf of the measured one
+		{"measured traced", func(b kitlb.Balancer, h *otelkittest.Harness) kitlb.Balancer {
+			return lb.MeasureBalancer(tracelb.TraceBalancer(b, h.Options()...), h.Options()...)
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			factory := lb.MeasureFactory(func(addr string) (endpoint.Endpoint, io.Closer, error) {
+				return func(context.Context, interface{}) (interface{}, error) {
+					if addr == "a" {
+						return nil, errDown
+					}
+					return addr, nil
+				}, nil, nil
+			}, h.Options()...)
+			a, _, _ := factory("a")
+			b, _, _ := factory("b")
+			balancer := tt.balance(&script{endpoints: []endpoint.Endpoint{nil, a, b}}, h)
+			ep := lb.MeasureRetry(h.Options()...)(kitlb.Retry(3, time.Second, balancer))
+
+			response, err := ep(context.Background(), nil)
+			if err != nil || response != "b" {
+				t.Fatalf("got %v, %v; want b", response, err)
+			}
+
+			h.RequireSum(t, "gokit.lb.retries", 2)
+			h.RequireNoMetric(t, "gokit.lb.exhausted")
+			h.RequireSum(t, "gokit.lb.selection.errors", 1)
+			h.RequireSum(t, "gokit.lb.selections", 1, attribute.String("gokit.lb.instance", "a"))
+			h.RequireSum(t, "gokit.lb.selections", 1, attribute.String("gokit.lb.instance", "b"))
+		})
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	h := otelkittest.New(t)
+	opts := h.Options(otelkit.WithOperationName("users"))
+	ep := lb.MeasureRetry(opts...)(kitlb.Retry(3, time.Second, lb.MeasureBalancer(&script{}, opts...)))
+
+	if _, err := ep(context.Background(), nil); err == nil {
+		t.Fatal("no error")
+	}
+
+	endpointName := attribute.String("gokit.endpoint", "users")
+	h.RequireSum(t, "gokit.lb.retries", 2, endpointName)
+	h.RequireSum(t, "gokit.lb.exhausted", 1, endpointName)
+	h.RequireSum(t, "gokit.lb.selection.errors", 3, endpointName)
+}
@@ -3,62 +3,91 @@ package kit
 import (
 	"context"
 	"net/http"
-	"time"
 
 	khttp "github.com/go-kit/kit/transport/http"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/metric"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/httpmetric"
+	"github.com/nnnewb/otelkit/internal/kitphase"
 )
 
-type startTimeKeyT struct{}
-type requestKeyT struct{}
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/kit"
 
-var startTimeKey startTimeKeyT
-var requestKey requestKeyT
+type measurementKeyT struct{}
 
-func MeasureServerBefore(meter metric.Meter) khttp.ServerOption {
-	// throughput
-	requestCounter, err := meter.Int64Counter("request-count")
-	if err != nil {
-		panic(err)
-	}
+var measurementKey measurementKeyT
 
-	return khttp.ServerBefore(func(ctx context.Context, request *http.Request) context.Context {
-		requestCounter.Add(
-			request.Context(), 1,
-			metric.WithAttributes(
-				attribute.String("url", request.URL.String()),
-				attribute.String("method", request.Method),
-				attribute.String("peer", request.RemoteAddr),
-			),
-		)
+// NewMeasureServerBefore marks the request as active and starts timing it,
+// the remaining metrics are recorded by NewMeasureServerFinalizer. Options
+// affecting the instruments, such as otelkit.WithLegacyMetrics, go here.
+func NewMeasureServerBefore(opts ...otelkit.Option) khttp.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	server := httpmetric.NewServer(cfg.Meter(ScopeName), cfg)
 
-		ctx = context.WithValue(ctx, startTimeKey, time.Now())
-		ctx = context.WithValue(ctx, requestKey, request)
-		return ctx
+	return khttp.ServerBefore(func(ctx context.Context, request *http.Request) context.Context {
+		if !cfg.Instrumented(request) {
+			return ctx
+		}
+		ctx = kitphase.Start(ctx)
+		return context.WithValue(ctx, measurementKey, server.Begin(request))
 	})
 }
 
-func MeasureServerFinalizer(meter metric.Meter) khttp.ServerOption {
-	// request duration
-	durationHistogram, err := meter.Int64Histogram("request-duration-milli")
-	if err != nil {
-		panic(err)
-	}
+// NewMeasureServerFinalizer records the request duration, status and sizes.
+// go-kit servers know no route template, pass otelkit.WithOperationName to
+// tell endpoints apart.
+func NewMeasureServerFinalizer(opts ...otelkit.Option) khttp.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
 
 	return khttp.ServerFinalizer(func(ctx context.Context, code int, r *http.Request) {
-		if req, ok := ctx.Value(requestKey).(*http.Request); ok {
-			if start, ok := ctx.Value(startTimeKey).(time.Time); ok {
-				durationHistogram.Record(
-					req.Context(),
-					time.Since(start).Milliseconds(),
-					metric.WithAttributes(
-						attribute.String("url", req.URL.String()),
-						attribute.String("method", req.Method),
-						attribute.String("peer", req.RemoteAddr),
-					),
-				)
-			}
+		if m, ok := ctx.Value(measurementKey).(*httpmetric.Measurement); ok {
+			size, _ := ctx.Value(khttp.ContextKeyResponseSize).(int64)
+			m.End(cfg.OperationName, code, size)
+		}
+	})
+}
+
+type clientMeasurementKeyT struct{}
+
+var clientMeasurementKey clientMeasurementKeyT
+
+// MeasureClientBefore starts timing the outbound request, the metrics are
+// recorded by MeasureClientFinalizer. Options affecting the instruments go
+// here.
+func MeasureClientBefore(opts ...otelkit.Option) khttp.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	client := httpmetric.NewClient(cfg.Meter(ScopeName), cfg)
+
+	return khttp.ClientBefore(func(ctx context.Context, request *http.Request) context.Context {
+		if !cfg.Instrumented(request) {
+			return ctx
+		}
+		m := client.Begin(ctx, request)
+		return m.ClientTrace(context.WithValue(ctx, clientMeasurementKey, m))
+	})
+}
+
+// MeasureClientAfter notes the response status and counts the response body
+// read by the decoder. The request is measured with the options given to
+// MeasureClientBefore, opts are accepted so all the hooks can be passed the
+// same ones.
+func MeasureClientAfter(opts ...otelkit.Option) khttp.ClientOption {
+	return khttp.ClientAfter(func(ctx context.Context, response *http.Response) context.Context {
+		if m, ok := ctx.Value(clientMeasurementKey).(*httpmetric.ClientMeasurement); ok {
+			m.Response(response)
+		}
+		return ctx
+	})
+}
+
+// MeasureClientFinalizer records http.client.request.duration, the body sizes
+// and the failed requests, keyed by target host, method and status. Like
+// MeasureClientAfter, it uses the options given to MeasureClientBefore.
+func MeasureClientFinalizer(opts ...otelkit.Option) khttp.ClientOption {
+	return khttp.ClientFinalizer(func(ctx context.Context, err error) {
+		if m, ok := ctx.Value(clientMeasurementKey).(*httpmetric.ClientMeasurement); ok {
+			m.End(err)
 		}
 	})
 }
@@ -0,0 +1,197 @@
+package kit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/metric/kit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func decodeRequest(context.Context, *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func newServer(h *otelkittest.Harness, e func(context.Context, interface{}) (interface{}, error)) *khttp.Server {
+	return khttp.NewServer(
+		kit.MeasureEndpoint("hello", h.Options()...)(e),
+		decodeRequest,
+		khttp.EncodeJSONResponse,
+		kit.NewMeasureServerBefore(h.Options()...),
+		kit.NewMeasureServerFinalizer(h.Options(otelkit.WithOperationName("/hello"))...),
+		khttp.ServerErrorHandler(kit.MeasureErrorHandler(h.Options(otelkit.WithOperationName("/hello"))...)))
+}
+
+func TestMeasureServer(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := newServer(h, func(context.Context, interface{}) (interface{}, error) {
+		return map[string]string{"msg": "hello"}, nil
+	})
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.String("http.route", "/hello"),
+		attribute.Int("http.response.status_code", http.StatusOK))
+	h.RequireHistogram(t, "gokit.endpoint.duration", 1,
+		attribute.String("gokit.endpoint", "hello"))
+	h.RequireSum(t, "http.server.active_requests", 0)
+	h.RequireNoMetric(t, "gokit.endpoint.errors")
+	h.RequireNoMetric(t, "gokit.server.errors")
+}
+
+func TestMeasureServerEndpointError(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := newServer(h, func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.New("endpoint failed")
+	})
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	h.RequireSum(t, "gokit.endpoint.errors", 1,
+		attribute.String("gokit.endpoint", "hello"))
+	h.RequireSum(t, "gokit.server.errors", 1,
+		attribute.String("gokit.error.phase", "endpoint"),
+		attribute.String("http.route", "/hello"))
+}
+
+func TestMeasureServerDecodeError(t *testing.T) {
+	decode := func(context.Context, *http.Request) (interface{}, error) {
+		return nil, errors.New("bad request")
+	}
+	for _, tt := range []struct {
+		name    string
+		decode  khttp.DecodeRequestFunc
+		handler bool
+		phase   string
+	}{
+		{"wrapped", kit.MeasureDecodeRequest(decode), true, "decode"},
+		{"unwrapped", decode, true, "unknown"},
+		{"error encoder only", kit.MeasureDecodeRequest(decode), false, "decode"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			opts := h.Options(otelkit.WithOperationName("/hello"))
+			serverOpts := []khttp.ServerOption{
+				kit.NewMeasureServerBefore(h.Options()...),
+				kit.NewMeasureServerFinalizer(opts...),
+				khttp.ServerErrorEncoder(kit.MeasureErrorEncoder(nil, opts...)),
+			}
+			if tt.handler {
+				serverOpts = append(serverOpts, khttp.ServerErrorHandler(kit.MeasureErrorHandler(opts...)))
+			}
+			srv := khttp.NewServer(kit.MeasureEndpoint("hello", h.Options()...)(hello),
+				tt.decode, khttp.EncodeJSONResponse, serverOpts...)
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+			// the default error encoder answered
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("response code = %d, want 500", rec.Code)
+			}
+			// counted once, also when both the handler and the encoder see it
+			h.RequireSum(t, "gokit.server.errors", 1,
+				attribute.String("gokit.error.phase", tt.phase),
+				attribute.String("http.route", "/hello"),
+				attribute.String("error.type", "*errors.errorString"))
+			h.RequireNoMetric(t, "gokit.endpoint.duration")
+		})
+	}
+}
+
+func hello(context.Context, interface{}) (interface{}, error) {
+	return map[string]string{"msg": "hello"}, nil
+}
+
+func TestMeasureServerCanceled(t *testing.T) {
+	h := otelkittest.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := newServer(h, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		// the client went away
+		cancel()
+		return nil, ctx.Err()
+	})
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil).WithContext(ctx))
+
+	h.RequireSum(t, "http.server.active_requests", 0)
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	h.RequireHistogram(t, "gokit.endpoint.duration", 1)
+	h.RequireSum(t, "gokit.endpoint.errors", 1)
+	h.RequireSum(t, "gokit.server.errors", 1, attribute.String("gokit.error.phase", "endpoint"))
+}
+
+func TestMeasureClient(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	client := khttp.NewClient(http.MethodGet, u,
+		khttp.EncodeJSONRequest,
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.MeasureClientBefore(h.Options()...),
+		kit.MeasureClientAfter(h.Options()...),
+		kit.MeasureClientFinalizer(h.Options()...))
+	if _, err := client.Endpoint()(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.RequireHistogram(t, "http.client.request.duration", 1,
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.String("server.address", "127.0.0.1"),
+		attribute.Int("http.response.status_code", http.StatusOK))
+	h.RequireSum(t, "http.client.active_requests", 0)
+	h.RequireNoMetric(t, "http.client.request.errors")
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(newServer(h, func(context.Context, interface{}) (interface{}, error) {
+		return map[string]string{"msg": "hello"}, nil
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/hello")
+	client := khttp.NewClient(http.MethodGet, u,
+		khttp.EncodeJSONRequest,
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.MeasureClientBefore(h.Options()...),
+		kit.MeasureClientAfter(),
+		kit.MeasureClientFinalizer())
+	if _, err := client.Endpoint()(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.RequireGolden(t, "golden", otelkittest.ScrubAttributes("http.connection.reused"))
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	meter := h.MeterProvider.Meter("legacy")
+	server := khttp.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return nil, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		kit.MeasureServerBefore(meter),
+		kit.MeasureServerFinalizer(meter))
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireHistogram(t, "http.server.request.duration", 1,
+		attribute.Int("http.response.status_code", http.StatusOK))
+	h.RequireSum(t, "http.server.active_requests", 0)
+}
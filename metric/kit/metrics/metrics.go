@@ -0,0 +1,167 @@
+// Package metrics implements the go-kit metrics interfaces on top of an
+// OpenTelemetry Meter, so code instrumented with
+// github.com/go-kit/kit/metrics reports to the same MeterProvider as the
+// otelkit middlewares.
+//
+// Label values passed to With are alternating keys and values, as with every
+// go-kit backend. A missing last value is "unknown".
+//
+// The provider subpackage offers the metrics as a go-kit provider.Provider.
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	kitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ScopeName is the instrumentation scope of the instruments created by this
+// package.
+const ScopeName = "github.com/nnnewb/otelkit/metric/kit/metrics"
+
+// labelValues are the alternating keys and values given to With.
+type labelValues []string
+
+func (lvs labelValues) with(labelValues ...string) labelValues {
+	if len(labelValues)%2 != 0 {
+		labelValues = append(labelValues, "unknown")
+	}
+	// copy, siblings created by With on the same metric must not share the
+	// backing array
+	return append(lvs[:len(lvs):len(lvs)], labelValues...)
+}
+
+func (lvs labelValues) set(base []attribute.KeyValue) attribute.Set {
+	attrs := make([]attribute.KeyValue, 0, len(base)+len(lvs)/2)
+	attrs = append(attrs, base...)
+	for i := 0; i+1 < len(lvs); i += 2 {
+		attrs = append(attrs, attribute.String(lvs[i], lvs[i+1]))
+	}
+	return attribute.NewSet(attrs...)
+}
+
+// Counter is a go-kit metrics.Counter recording to a Float64Counter.
+type Counter struct {
+	counter metric.Float64Counter
+	base    []attribute.KeyValue
+	lvs     labelValues
+}
+
+// NewCounter creates the counter name. It panics if the instrument can not be
+// created.
+func NewCounter(name string, opts ...otelkit.Option) *Counter {
+	cfg := otelkit.NewConfig(opts...)
+	counter, err := cfg.Meter(ScopeName).Float64Counter(name)
+	if err != nil {
+		panic(err)
+	}
+	return &Counter{counter: counter, base: cfg.Attributes}
+}
+
+// With implements metrics.Counter.
+func (c *Counter) With(labelValues ...string) kitmetrics.Counter {
+	return &Counter{counter: c.counter, base: c.base, lvs: c.lvs.with(labelValues...)}
+}
+
+// Add implements metrics.Counter.
+func (c *Counter) Add(delta float64) {
+	c.counter.Add(context.Background(), delta, metric.WithAttributeSet(c.lvs.set(c.base)))
+}
+
+// Gauge is a go-kit metrics.Gauge reported by a Float64ObservableGauge. The
+// last value set for each label set is observed on collection.
+type Gauge struct {
+	values *gaugeValues
+	base   []attribute.KeyValue
+	lvs    labelValues
+}
+
+type gaugeValues struct {
+	mu     sync.Mutex
+	values map[attribute.Distinct]*gaugeValue
+}
+
+type gaugeValue struct {
+	set   attribute.Set
+	value float64
+}
+
+// NewGauge creates the gauge name. It panics if the instrument can not be
+// created.
+func NewGauge(name string, opts ...otelkit.Option) *Gauge {
+	cfg := otelkit.NewConfig(opts...)
+	values := &gaugeValues{values: map[attribute.Distinct]*gaugeValue{}}
+	_, err := cfg.Meter(ScopeName).Float64ObservableGauge(name,
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			values.mu.Lock()
+			defer values.mu.Unlock()
+			for _, v := range values.values {
+				o.Observe(v.value, metric.WithAttributeSet(v.set))
+			}
+			return nil
+		}))
+	if err != nil {
+		panic(err)
+	}
+	return &Gauge{values: values, base: cfg.Attributes}
+}
+
+// With implements metrics.Gauge.
+func (g *Gauge) With(labelValues ...string) kitmetrics.Gauge {
+	return &Gauge{values: g.values, base: g.base, lvs: g.lvs.with(labelValues...)}
+}
+
+// Set implements metrics.Gauge.
+func (g *Gauge) Set(value float64) {
+	g.update(func(v *gaugeValue) { v.value = value })
+}
+
+// Add implements metrics.Gauge.
+func (g *Gauge) Add(delta float64) {
+	g.update(func(v *gaugeValue) { v.value += delta })
+}
+
+func (g *Gauge) update(fn func(v *gaugeValue)) {
+	set := g.lvs.set(g.base)
+	g.values.mu.Lock()
+	defer g.values.mu.Unlock()
+	v, ok := g.values.values[set.Equivalent()]
+	if !ok {
+		v = &gaugeValue{set: set}
+		g.values.values[set.Equivalent()] = v
+	}
+	fn(v)
+}
+
+// Histogram is a go-kit metrics.Histogram recording to a Float64Histogram.
+// Its buckets are chosen by the views of the MeterProvider.
+type Histogram struct {
+	histogram metric.Float64Histogram
+	base      []attribute.KeyValue
+	lvs       labelValues
+}
+
+// NewHistogram creates the histogram name. It panics if the instrument can not
+// be created.
+func NewHistogram(name string, opts ...otelkit.Option) *Histogram {
+	cfg := otelkit.NewConfig(opts...)
+	histogram, err := cfg.Meter(ScopeName).Float64Histogram(name)
+	if err != nil {
+		panic(err)
+	}
+	return &Histogram{histogram: histogram, base: cfg.Attributes}
+}
+
+// With implements metrics.Histogram.
+func (h *Histogram) With(labelValues ...string) kitmetrics.Histogram {
+	return &Histogram{histogram: h.histogram, base: h.base, lvs: h.lvs.with(labelValues...)}
+}
+
+// Observe implements metrics.Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.histogram.Record(context.Background(), value, metric.WithAttributeSet(h.lvs.set(h.base)))
+}
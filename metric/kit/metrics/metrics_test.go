@@ -0,0 +1,101 @@
+package metrics_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/metric/kit/metrics"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// values returns the points of the counter or gauge name by their exact
+// attributes, encoded as "k=v,k=v".
+func values(t *testing.T, h *otelkittest.Harness, name string) map[string]float64 {
+	t.Helper()
+	m, ok := h.Metric(t, name)
+	if !ok {
+		t.Fatalf("%q not recorded", name)
+	}
+	var points []metricdata.DataPoint[float64]
+	switch data := m.Data.(type) {
+	case metricdata.Sum[float64]:
+		points = data.DataPoints
+	case metricdata.Gauge[float64]:
+		points = data.DataPoints
+	default:
+		t.Fatalf("%q is a %T", name, m.Data)
+	}
+	got := map[string]float64{}
+	for _, dp := range points {
+		got[dp.Attributes.Encoded(attribute.DefaultEncoder())] = dp.Value
+	}
+	return got
+}
+
+func TestCounterWith(t *testing.T) {
+	h := otelkittest.New(t)
+	counter := metrics.NewCounter("requests", h.Options(otelkit.WithAttributes(attribute.String("team", "payments")))...)
+
+	counter.Add(1)
+	get := counter.With("method", "GET")
+	get.Add(2)
+	// siblings of the same parent must not see each other's labels
+	get.With("code", "200").Add(3)
+	get.With("code", "500").Add(4)
+	counter.With("method").Add(5)
+
+	want := map[string]float64{
+		"team=payments":                     1,
+		"method=GET,team=payments":          2,
+		"code=200,method=GET,team=payments": 3,
+		"code=500,method=GET,team=payments": 4,
+		"method=unknown,team=payments":      5,
+	}
+	if got := values(t, h, "requests"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	h := otelkittest.New(t)
+	gauge := metrics.NewGauge("queue_depth", h.Options()...)
+
+	gauge.Set(3)
+	gauge.Add(2)
+	high := gauge.With("priority", "high")
+	high.Add(1)
+	high.Add(1)
+	gauge.With("priority", "low").Set(7)
+	gauge.With("priority", "low").Set(4)
+
+	want := map[string]float64{
+		"":              5,
+		"priority=high": 2,
+		"priority=low":  4,
+	}
+	if got := values(t, h, "queue_depth"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// the last value is observed again on every collection
+	gauge.Set(1)
+	want[""] = 1
+	if got := values(t, h, "queue_depth"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHistogramWith(t *testing.T) {
+	h := otelkittest.New(t)
+	histogram := metrics.NewHistogram("latency", h.Options()...)
+
+	histogram.Observe(0.1)
+	histogram.With("method", "GET").Observe(0.2)
+	histogram.With("method", "GET").Observe(0.3)
+
+	h.RequireHistogram(t, "latency", 3)
+	h.RequireHistogram(t, "latency", 2, attribute.String("method", "GET"))
+}
@@ -0,0 +1,38 @@
+// Package provider adapts the metrics of
+// github.com/nnnewb/otelkit/metric/kit/metrics to go-kit's provider.Provider.
+// It lives apart because importing github.com/go-kit/kit/metrics/provider
+// links every go-kit metrics backend.
+package provider
+
+import (
+	kitmetrics "github.com/go-kit/kit/metrics"
+	kitprovider "github.com/go-kit/kit/metrics/provider"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/metric/kit/metrics"
+)
+
+// NewProvider returns a go-kit provider.Provider creating the metrics of the
+// metrics package with opts. The bucket count given to NewHistogram is
+// ignored, configure buckets with a view instead.
+func NewProvider(opts ...otelkit.Option) kitprovider.Provider {
+	return otelProvider{opts: opts}
+}
+
+type otelProvider struct {
+	opts []otelkit.Option
+}
+
+func (p otelProvider) NewCounter(name string) kitmetrics.Counter {
+	return metrics.NewCounter(name, p.opts...)
+}
+
+func (p otelProvider) NewGauge(name string) kitmetrics.Gauge {
+	return metrics.NewGauge(name, p.opts...)
+}
+
+func (p otelProvider) NewHistogram(name string, _ int) kitmetrics.Histogram {
+	return metrics.NewHistogram(name, p.opts...)
+}
+
+// Stop does nothing, the MeterProvider is shut down by its owner.
+func (p otelProvider) Stop() {}
@@ -0,0 +1,25 @@
+package provider_test
+
+import (
+	"testing"
+
+	"github.com/nnnewb/otelkit/metric/kit/metrics/provider"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewProvider(t *testing.T) {
+	h := otelkittest.New(t)
+	p := provider.NewProvider(h.Options()...)
+	defer p.Stop()
+
+	p.NewCounter("requests").With("method", "GET").Add(1)
+	p.NewHistogram("latency", 50).Observe(0.1)
+	p.NewGauge("queue_depth").Set(3)
+
+	h.RequireSum(t, "requests", 1, attribute.String("method", "GET"))
+	h.RequireHistogram(t, "latency", 1)
+	if _, ok := h.Metric(t, "queue_depth"); !ok {
+		t.Error("queue_depth not observed")
+	}
+}
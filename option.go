@@ -0,0 +1,182 @@
+// Package otelkit holds the configuration shared by the tracing and metric
+// middlewares of this module.
+//
+// Every middleware constructor accepts a list of Option. Unless told
+// otherwise, middlewares use the global TracerProvider, MeterProvider and
+// TextMapPropagator registered with the otel package.
+package otelkit
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config is the resolved configuration of a middleware. It is built by
+// NewConfig from a list of Option and read by the middleware packages.
+type Config struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+	Propagators    propagation.TextMapPropagator
+
+	Filters    []Filter
+	Attributes []attribute.KeyValue
+
+	StatusClassifier  StatusClassifier
+	SpanNameFormatter SpanNameFormatter
+	OperationName     string
+
+	MetricAttributesFunc MetricAttributesFunc
+	LegacyMetrics        bool
+
+	Recovery RecoveryMode
+
+	headers headerCapture
+	tracer  trace.Tracer
+	meter   metric.Meter
+	values  map[interface{}]interface{}
+}
+
+// Option customizes a middleware.
+type Option func(*Config)
+
+// Filter reports whether req should be instrumented.
+type Filter func(req *http.Request) bool
+
+// NewConfig applies opts on top of the defaults.
+func NewConfig(opts ...Option) *Config {
+	cfg := &Config{
+		headers: newHeaderCapture(),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.MeterProvider == nil {
+		cfg.MeterProvider = otel.GetMeterProvider()
+	}
+	if cfg.Propagators == nil {
+		cfg.Propagators = otel.GetTextMapPropagator()
+	}
+	return cfg
+}
+
+// WithTracerProvider sets the TracerProvider spans are created with.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(cfg *Config) {
+		cfg.TracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the MeterProvider instruments are created with.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(cfg *Config) {
+		cfg.MeterProvider = provider
+	}
+}
+
+// WithTracer creates spans with tracer instead of a tracer of the
+// TracerProvider, for the positional constructors of earlier releases.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(cfg *Config) {
+		cfg.tracer = tracer
+	}
+}
+
+// WithMeter creates instruments with meter instead of a meter of the
+// MeterProvider, for the positional constructors of earlier releases.
+func WithMeter(meter metric.Meter) Option {
+	return func(cfg *Config) {
+		cfg.meter = meter
+	}
+}
+
+// WithPropagators sets the propagator extracting and injecting the trace
+// context.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(cfg *Config) {
+		cfg.Propagators = propagators
+	}
+}
+
+// WithFilter skips the instrumentation of requests for which filter returns
+// false. When given several times a request is instrumented only if every
+// filter returns true.
+func WithFilter(filter Filter) Option {
+	return func(cfg *Config) {
+		cfg.Filters = append(cfg.Filters, filter)
+	}
+}
+
+// WithAttributes adds attrs to every span and request metric.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(cfg *Config) {
+		cfg.Attributes = append(cfg.Attributes, attrs...)
+	}
+}
+
+// WithStatusClassifier overrides how HTTP status codes and errors map to span
+// status.
+func WithStatusClassifier(classifier StatusClassifier) Option {
+	return func(cfg *Config) {
+		cfg.StatusClassifier = classifier
+	}
+}
+
+// Tracer returns the tracer of the instrumentation scope name, or the one
+// given by WithTracer.
+func (c *Config) Tracer(name string) trace.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return c.TracerProvider.Tracer(name)
+}
+
+// Meter returns the meter of the instrumentation scope name, or the one given
+// by WithMeter.
+func (c *Config) Meter(name string) metric.Meter {
+	if c.meter != nil {
+		return c.meter
+	}
+	return c.MeterProvider.Meter(name)
+}
+
+// SetValue stores value under key, for options defined by the middleware
+// packages themselves. Use an unexported key type, as with context.WithValue.
+func (c *Config) SetValue(key, value interface{}) {
+	if c.values == nil {
+		c.values = map[interface{}]interface{}{}
+	}
+	c.values[key] = value
+}
+
+// Value returns the value stored under key by SetValue, or nil.
+func (c *Config) Value(key interface{}) interface{} {
+	return c.values[key]
+}
+
+// Instrumented reports whether req passes all filters.
+func (c *Config) Instrumented(req *http.Request) bool {
+	for _, filter := range c.Filters {
+		if !filter(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// Classifier returns the configured StatusClassifier, or def when none was
+// set. Server side middlewares pass ServerStatus, client side ones
+// ClientStatus.
+func (c *Config) Classifier(def StatusClassifier) StatusClassifier {
+	if c.StatusClassifier != nil {
+		return c.StatusClassifier
+	}
+	return def
+}
@@ -0,0 +1,233 @@
+// Package otelkittest records the spans and metrics of the otelkit middlewares
+// in memory, for tests.
+//
+//	h := otelkittest.New(t)
+//	handler := tracehttp.NewTraceHandler(h.Options()...)(mux)
+//	...
+//	h.RequireSpan(t, trace.SpanKindServer, "GET /users/{id}",
+//		attribute.Int("http.status_code", 404))
+//	h.RequireHistogram(t, "http.server.request.duration", 1,
+//		attribute.String("http.route", "/users/{id}"))
+package otelkittest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Harness wires an in-memory span recorder and metric reader into a
+// TracerProvider and a MeterProvider.
+type Harness struct {
+	Recorder *tracetest.SpanRecorder
+	Reader   sdkmetric.Reader
+
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+}
+
+// New creates a Harness, shut down when tb completes.
+func New(tb testing.TB) *Harness {
+	tb.Helper()
+	h := &Harness{
+		Recorder: tracetest.NewSpanRecorder(),
+		Reader:   sdkmetric.NewManualReader(),
+	}
+	h.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(h.Recorder))
+	h.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(h.Reader))
+	tb.Cleanup(func() {
+		_ = h.TracerProvider.Shutdown(context.Background())
+		_ = h.MeterProvider.Shutdown(context.Background())
+	})
+	return h
+}
+
+// Options returns the options pointing a middleware at the harness providers
+// and the W3C trace context propagator, followed by opts.
+func (h *Harness) Options(opts ...otelkit.Option) []otelkit.Option {
+	return append([]otelkit.Option{
+		otelkit.WithTracerProvider(h.TracerProvider),
+		otelkit.WithMeterProvider(h.MeterProvider),
+		otelkit.WithPropagators(propagation.TraceContext{}),
+	}, opts...)
+}
+
+// Spans returns the spans ended so far, in the order they ended.
+func (h *Harness) Spans() []sdktrace.ReadOnlySpan {
+	return h.Recorder.Ended()
+}
+
+// Collect returns the metrics recorded so far.
+func (h *Harness) Collect(tb testing.TB) metricdata.ResourceMetrics {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := h.Reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatalf("otelkittest: collect metrics: %v", err)
+	}
+	return rm
+}
+
+// Metric returns the metric named name, if any was recorded.
+func (h *Harness) Metric(tb testing.TB, name string) (metricdata.Metrics, bool) {
+	tb.Helper()
+	rm := h.Collect(tb)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+// RequireSpan fails tb unless exactly one ended span has the given kind and
+// name and carries all of attrs. It returns that span.
+func (h *Harness) RequireSpan(tb testing.TB, kind trace.SpanKind, name string, attrs ...attribute.KeyValue) sdktrace.ReadOnlySpan {
+	tb.Helper()
+	var found []sdktrace.ReadOnlySpan
+	for _, span := range h.Spans() {
+		if span.SpanKind() == kind && span.Name() == name && contains(span.Attributes(), attrs) {
+			found = append(found, span)
+		}
+	}
+	if len(found) != 1 {
+		tb.Fatalf("otelkittest: want 1 %s span %q with %s, found %d among:\n%s",
+			kind, name, formatAttributes(attrs), len(found), h.describeSpans())
+	}
+	return found[0]
+}
+
+// RequireSpanCount fails tb unless n spans ended.
+func (h *Harness) RequireSpanCount(tb testing.TB, n int) {
+	tb.Helper()
+	if spans := h.Spans(); len(spans) != n {
+		tb.Fatalf("otelkittest: want %d spans, found %d:\n%s", n, len(spans), h.describeSpans())
+	}
+}
+
+// RequireHistogram fails tb unless the histogram named name holds count
+// observations in the data points carrying all of attrs.
+func (h *Harness) RequireHistogram(tb testing.TB, name string, count uint64, attrs ...attribute.KeyValue) {
+	tb.Helper()
+	m, ok := h.Metric(tb, name)
+	if !ok {
+		tb.Fatalf("otelkittest: histogram %q not recorded", name)
+	}
+	var got uint64
+	var points []attribute.Set
+	switch data := m.Data.(type) {
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, dp.Attributes)
+			if contains(dp.Attributes.ToSlice(), attrs) {
+				got += dp.Count
+			}
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, dp.Attributes)
+			if contains(dp.Attributes.ToSlice(), attrs) {
+				got += dp.Count
+			}
+		}
+	default:
+		tb.Fatalf("otelkittest: %q is a %T, not a histogram", name, m.Data)
+	}
+	if got != count {
+		tb.Fatalf("otelkittest: want %d observations of %q with %s, found %d in:\n%s",
+			count, name, formatAttributes(attrs), got, describePoints(points))
+	}
+}
+
+// RequireSum fails tb unless the counter or up-down counter named name adds up
+// to value in the data points carrying all of attrs.
+func (h *Harness) RequireSum(tb testing.TB, name string, value float64, attrs ...attribute.KeyValue) {
+	tb.Helper()
+	m, ok := h.Metric(tb, name)
+	if !ok {
+		tb.Fatalf("otelkittest: sum %q not recorded", name)
+	}
+	var got float64
+	var points []attribute.Set
+	switch data := m.Data.(type) {
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, dp.Attributes)
+			if contains(dp.Attributes.ToSlice(), attrs) {
+				got += dp.Value
+			}
+		}
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			points = append(points, dp.Attributes)
+			if contains(dp.Attributes.ToSlice(), attrs) {
+				got += float64(dp.Value)
+			}
+		}
+	default:
+		tb.Fatalf("otelkittest: %q is a %T, not a sum", name, m.Data)
+	}
+	if got != value {
+		tb.Fatalf("otelkittest: want %q with %s to be %v, found %v in:\n%s",
+			name, formatAttributes(attrs), value, got, describePoints(points))
+	}
+}
+
+// RequireNoMetric fails tb if the metric named name was recorded.
+func (h *Harness) RequireNoMetric(tb testing.TB, name string) {
+	tb.Helper()
+	if _, ok := h.Metric(tb, name); ok {
+		tb.Fatalf("otelkittest: want no %q, found one", name)
+	}
+}
+
+func contains(have, want []attribute.KeyValue) bool {
+	for _, w := range want {
+		found := false
+		for _, kv := range have {
+			if kv.Key == w.Key && kv.Value == w.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func formatAttributes(attrs []attribute.KeyValue) string {
+	parts := make([]string, 0, len(attrs))
+	for _, kv := range attrs {
+		parts = append(parts, fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit()))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (h *Harness) describeSpans() string {
+	var b strings.Builder
+	for _, span := range h.Spans() {
+		fmt.Fprintf(&b, "\t%s %q %s\n", span.SpanKind(), span.Name(), formatAttributes(span.Attributes()))
+	}
+	return b.String()
+}
+
+func describePoints(points []attribute.Set) string {
+	var b strings.Builder
+	for _, set := range points {
+		fmt.Fprintf(&b, "\t%s\n", formatAttributes(set.ToSlice()))
+	}
+	return b.String()
+}
@@ -0,0 +1,370 @@
+package otelkittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var update = flag.Bool("otelkittest.update", false, "rewrite the otelkittest golden files")
+
+// Scrubbed replaces the value of attributes which change from run to run.
+const Scrubbed = "<scrubbed>"
+
+// DefaultScrubbedAttributes are always scrubbed from snapshots: peer
+// addresses and ports of test servers, stack traces and the headers carrying
+// trace IDs or dates.
+var DefaultScrubbedAttributes = []attribute.Key{
+	"exception.stacktrace",
+	"http.url",
+	"net.sock.peer.addr",
+	"net.sock.peer.port",
+	"net.peer.port",
+	"server.port",
+	"http.request.header.Traceparent",
+	"http.response.header.Date",
+}
+
+// SnapshotOption customizes a snapshot.
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	scrub       map[attribute.Key]struct{}
+	ignoreScope bool
+	ignore      map[string]struct{}
+}
+
+// ScrubAttributes scrubs the values of keys on top of
+// DefaultScrubbedAttributes.
+func ScrubAttributes(keys ...attribute.Key) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		for _, key := range keys {
+			cfg.scrub[key] = struct{}{}
+		}
+	}
+}
+
+// IgnoreScope leaves out instrumentation scope names, to compare the
+// telemetry of different packages.
+func IgnoreScope() SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		cfg.ignoreScope = true
+	}
+}
+
+// IgnoreMetrics leaves out the metrics called names, to compare packages
+// which emit instruments of their own.
+func IgnoreMetrics(names ...string) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		for _, name := range names {
+			cfg.ignore[name] = struct{}{}
+		}
+	}
+}
+
+// Snapshot serializes the ended spans and the collected metrics into stable
+// JSON. Trace and span IDs are replaced by their order of appearance,
+// timestamps are dropped, histograms keep their observation count only,
+// plus their sum when they measure sizes in bytes, and volatile attributes are
+// scrubbed.
+func (h *Harness) Snapshot(tb testing.TB, opts ...SnapshotOption) []byte {
+	tb.Helper()
+	cfg := &snapshotConfig{scrub: map[attribute.Key]struct{}{}, ignore: map[string]struct{}{}}
+	for _, key := range DefaultScrubbedAttributes {
+		cfg.scrub[key] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	snapshot := struct {
+		Spans   []spanSnapshot   `json:"spans"`
+		Metrics []metricSnapshot `json:"metrics"`
+	}{
+		Spans:   cfg.spans(h.Spans()),
+		Metrics: cfg.metrics(h.Collect(tb)),
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snapshot); err != nil {
+		tb.Fatalf("otelkittest: marshal snapshot: %v", err)
+	}
+	return b.Bytes()
+}
+
+// RequireGolden fails tb unless the snapshot matches testdata/<name>.json.
+// Run the test with -otelkittest.update to rewrite the file.
+func (h *Harness) RequireGolden(tb testing.TB, name string, opts ...SnapshotOption) {
+	tb.Helper()
+	got := h.Snapshot(tb, opts...)
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("otelkittest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("otelkittest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("otelkittest: %v, run with -otelkittest.update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		tb.Fatalf("otelkittest: snapshot differs from %s, run with -otelkittest.update to accept:\n%s",
+			path, Diff(string(want), string(got)))
+	}
+}
+
+type spanSnapshot struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	Scope      string                 `json:"scope,omitempty"`
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	Parent     string                 `json:"parent,omitempty"`
+	Status     string                 `json:"status"`
+	StatusDesc string                 `json:"status_description,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Events     []eventSnapshot        `json:"events,omitempty"`
+}
+
+type eventSnapshot struct {
+	Name       string                 `json:"name"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func (cfg *snapshotConfig) spans(spans []sdktrace.ReadOnlySpan) []spanSnapshot {
+	snapshots := make([]spanSnapshot, len(spans))
+	for i, span := range spans {
+		s := spanSnapshot{
+			Name:       span.Name(),
+			Kind:       span.SpanKind().String(),
+			Scope:      cfg.scope(span.InstrumentationScope().Name),
+			Status:     span.Status().Code.String(),
+			StatusDesc: span.Status().Description,
+			Attributes: cfg.attributes(span.Attributes()),
+		}
+		for _, event := range span.Events() {
+			s.Events = append(s.Events, eventSnapshot{
+				Name:       event.Name,
+				Attributes: cfg.attributes(event.Attributes),
+			})
+		}
+		snapshots[i] = s
+	}
+
+	// spans end in no particular order when several goroutines are involved,
+	// sort them by content before numbering their IDs.
+	order := make([]int, len(spans))
+	keys := make([]string, len(spans))
+	for i := range spans {
+		order[i] = i
+		b, _ := json.Marshal(snapshots[i])
+		keys[i] = string(b)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+
+	traceIDs := map[trace.TraceID]string{}
+	spanIDs := map[trace.SpanID]string{}
+	for _, i := range order {
+		sc := spans[i].SpanContext()
+		if _, ok := traceIDs[sc.TraceID()]; !ok {
+			traceIDs[sc.TraceID()] = fmt.Sprintf("trace-%d", len(traceIDs)+1)
+		}
+		spanIDs[sc.SpanID()] = fmt.Sprintf("span-%d", len(spanIDs)+1)
+	}
+
+	sorted := make([]spanSnapshot, 0, len(spans))
+	for _, i := range order {
+		s := snapshots[i]
+		s.TraceID = traceIDs[spans[i].SpanContext().TraceID()]
+		s.SpanID = spanIDs[spans[i].SpanContext().SpanID()]
+		if parent := spans[i].Parent(); parent.IsValid() {
+			if id, ok := spanIDs[parent.SpanID()]; ok {
+				s.Parent = id
+			} else {
+				s.Parent = "unrecorded"
+			}
+		}
+		sorted = append(sorted, s)
+	}
+	return sorted
+}
+
+type metricSnapshot struct {
+	Scope       string          `json:"scope,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Unit        string          `json:"unit,omitempty"`
+	Type        string          `json:"type"`
+	DataPoints  []pointSnapshot `json:"data_points"`
+}
+
+type pointSnapshot struct {
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Count      *uint64                `json:"count,omitempty"`
+	Sum        interface{}            `json:"sum,omitempty"`
+	Value      interface{}            `json:"value,omitempty"`
+}
+
+func (cfg *snapshotConfig) metrics(rm metricdata.ResourceMetrics) []metricSnapshot {
+	snapshots := []metricSnapshot{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if _, ok := cfg.ignore[m.Name]; ok {
+				continue
+			}
+			s := metricSnapshot{
+				Scope:       cfg.scope(sm.Scope.Name),
+				Name:        m.Name,
+				Description: m.Description,
+				Unit:        m.Unit,
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				s.Type = "histogram"
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.histogramPoint(m.Unit, dp.Attributes, dp.Count, dp.Sum))
+				}
+			case metricdata.Histogram[int64]:
+				s.Type = "histogram"
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.histogramPoint(m.Unit, dp.Attributes, dp.Count, dp.Sum))
+				}
+			case metricdata.Sum[float64]:
+				s.Type = sumType(data.IsMonotonic)
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.valuePoint(dp.Attributes, dp.Value))
+				}
+			case metricdata.Sum[int64]:
+				s.Type = sumType(data.IsMonotonic)
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.valuePoint(dp.Attributes, dp.Value))
+				}
+			case metricdata.Gauge[float64]:
+				s.Type = "gauge"
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.valuePoint(dp.Attributes, dp.Value))
+				}
+			case metricdata.Gauge[int64]:
+				s.Type = "gauge"
+				for _, dp := range data.DataPoints {
+					s.DataPoints = append(s.DataPoints, cfg.valuePoint(dp.Attributes, dp.Value))
+				}
+			default:
+				s.Type = fmt.Sprintf("%T", m.Data)
+			}
+			sort.Slice(s.DataPoints, func(a, b int) bool {
+				ka, _ := json.Marshal(s.DataPoints[a].Attributes)
+				kb, _ := json.Marshal(s.DataPoints[b].Attributes)
+				return string(ka) < string(kb)
+			})
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.SliceStable(snapshots, func(a, b int) bool {
+		if snapshots[a].Scope != snapshots[b].Scope {
+			return snapshots[a].Scope < snapshots[b].Scope
+		}
+		return snapshots[a].Name < snapshots[b].Name
+	})
+	return snapshots
+}
+
+func (cfg *snapshotConfig) scope(name string) string {
+	if cfg.ignoreScope {
+		return ""
+	}
+	return name
+}
+
+func sumType(monotonic bool) string {
+	if monotonic {
+		return "counter"
+	}
+	return "updowncounter"
+}
+
+func (cfg *snapshotConfig) histogramPoint(unit string, attrs attribute.Set, count uint64, sum interface{}) pointSnapshot {
+	p := pointSnapshot{Attributes: cfg.attributes(attrs.ToSlice()), Count: &count}
+	// durations vary from run to run, sizes don't
+	if unit == "By" {
+		p.Sum = sum
+	}
+	return p
+}
+
+func (cfg *snapshotConfig) valuePoint(attrs attribute.Set, value interface{}) pointSnapshot {
+	return pointSnapshot{Attributes: cfg.attributes(attrs.ToSlice()), Value: value}
+}
+
+func (cfg *snapshotConfig) attributes(attrs []attribute.KeyValue) map[string]interface{} {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		if _, ok := cfg.scrub[kv.Key]; ok {
+			m[string(kv.Key)] = Scrubbed
+			continue
+		}
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
+
+// Diff returns a line diff of want and got, lines only in want prefixed by
+// "-" and lines only in got by "+".
+func Diff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,177 @@
+package otelkittest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nnnewb/otelkit/otelkittest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Packages using otelkittest keep their own -update flag for golden files.
+var _ = flag.Bool("update", false, "rewrite golden files")
+
+func TestDiff(t *testing.T) {
+	got := otelkittest.Diff("a\nb\nc\n", "a\nx\nc\n")
+	want := "-b\n+x\n"
+	if got != want {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+}
+
+// snapshot is the decoded form of Harness.Snapshot.
+type snapshot struct {
+	Spans []struct {
+		Name       string                 `json:"name"`
+		TraceID    string                 `json:"trace_id"`
+		SpanID     string                 `json:"span_id"`
+		Parent     string                 `json:"parent"`
+		Attributes map[string]interface{} `json:"attributes"`
+	} `json:"spans"`
+	Metrics []struct {
+		Name       string `json:"name"`
+		DataPoints []struct {
+			Count *uint64     `json:"count"`
+			Sum   interface{} `json:"sum"`
+		} `json:"data_points"`
+	} `json:"metrics"`
+}
+
+func decode(t *testing.T, b []byte) snapshot {
+	t.Helper()
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// tree records a request span with two children, ending the children in the
+// given order.
+func tree(h *otelkittest.Harness, order ...string) {
+	tr := h.TracerProvider.Tracer("test")
+	ctx, root := tr.Start(context.Background(), "request")
+	children := map[string]trace.Span{}
+	for _, name := range []string{"decode", "encode"} {
+		_, children[name] = tr.Start(ctx, name)
+	}
+	for _, name := range order {
+		children[name].End()
+	}
+	root.End()
+}
+
+func TestSnapshotOrder(t *testing.T) {
+	a := otelkittest.New(t)
+	tree(a, "decode", "encode")
+	b := otelkittest.New(t)
+	tree(b, "encode", "decode")
+
+	want, got := a.Snapshot(t), b.Snapshot(t)
+	if !bytes.Equal(got, want) {
+		t.Errorf("snapshot depends on the end order:\n%s", otelkittest.Diff(string(want), string(got)))
+	}
+}
+
+func TestSnapshotIDs(t *testing.T) {
+	h := otelkittest.New(t)
+	tree(h, "decode", "encode")
+	tree(h, "decode", "encode")
+
+	s := decode(t, h.Snapshot(t))
+	traces := map[string]int{}
+	spans := map[string]string{}
+	for _, span := range s.Spans {
+		traces[span.TraceID]++
+		spans[span.SpanID] = span.Name
+	}
+	if len(traces) != 2 || traces["trace-1"] != 3 || traces["trace-2"] != 3 {
+		t.Errorf("traces = %v, want trace-1 and trace-2 with 3 spans each", traces)
+	}
+	for i := 1; i <= 6; i++ {
+		if _, ok := spans[fmt.Sprintf("span-%d", i)]; !ok {
+			t.Errorf("no span-%d among %v", i, spans)
+		}
+	}
+	for _, span := range s.Spans {
+		if span.Name == "request" {
+			continue
+		}
+		if spans[span.Parent] != "request" {
+			t.Errorf("%s parent = %q, want a request span", span.Name, span.Parent)
+		}
+	}
+}
+
+func TestSnapshotUnrecordedParent(t *testing.T) {
+	h := otelkittest.New(t)
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+	_, span := h.TracerProvider.Tracer("test").Start(ctx, "server")
+	span.End()
+
+	s := decode(t, h.Snapshot(t))
+	if len(s.Spans) != 1 || s.Spans[0].Parent != "unrecorded" || s.Spans[0].TraceID != "trace-1" {
+		t.Errorf("spans = %+v, want one with an unrecorded parent", s.Spans)
+	}
+}
+
+func TestSnapshotScrub(t *testing.T) {
+	h := otelkittest.New(t)
+	_, span := h.TracerProvider.Tracer("test").Start(context.Background(), "server")
+	span.SetAttributes(
+		attribute.Int("server.port", 54321),
+		attribute.String("user.id", "42"),
+		attribute.String("http.route", "/users"))
+	span.End()
+
+	s := decode(t, h.Snapshot(t, otelkittest.ScrubAttributes("user.id")))
+	want := map[string]interface{}{
+		"server.port": otelkittest.Scrubbed,
+		"user.id":     otelkittest.Scrubbed,
+		"http.route":  "/users",
+	}
+	if got := s.Spans[0].Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotHistogramSums(t *testing.T) {
+	h := otelkittest.New(t)
+	meter := h.MeterProvider.Meter("test")
+	size, _ := meter.Int64Histogram("body.size", metric.WithUnit("By"))
+	size.Record(context.Background(), 100)
+	size.Record(context.Background(), 20)
+	duration, _ := meter.Float64Histogram("duration", metric.WithUnit("s"))
+	duration.Record(context.Background(), 0.25)
+
+	metrics := decode(t, h.Snapshot(t)).Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(metrics))
+	}
+	for _, m := range metrics {
+		p := m.DataPoints[0]
+		switch m.Name {
+		case "body.size":
+			if p.Count == nil || *p.Count != 2 || p.Sum != 120.0 {
+				t.Errorf("body.size = %v observations summing to %v, want 2 and 120", p.Count, p.Sum)
+			}
+		case "duration":
+			if p.Count == nil || *p.Count != 1 || p.Sum != nil {
+				t.Errorf("duration = %v observations summing to %v, want 1 and no sum", p.Count, p.Sum)
+			}
+		}
+	}
+}
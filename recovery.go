@@ -0,0 +1,49 @@
+package otelkit
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecoveryMode selects what server middlewares do when the handler panics.
+type RecoveryMode int
+
+const (
+	// RecoveryDisabled leaves panics alone, the default.
+	RecoveryDisabled RecoveryMode = iota
+	// RecoveryRepanic records the panic and panics again, leaving the response
+	// to an outer recovery handler.
+	RecoveryRepanic
+	// RecoveryRespond records the panic and answers 500 Internal Server Error
+	// if nothing was written yet.
+	RecoveryRespond
+)
+
+// WithPanicRecovery makes server middlewares recover handler panics: the span
+// gets an exception event with the panic value and stack trace and is marked
+// as failed, and the request is measured as a 500. Then, depending on mode,
+// the panic is resumed or a 500 response is written.
+//
+// When stacking several middlewares, resume the panic in the inner ones so
+// every middleware sees it.
+func WithPanicRecovery(mode RecoveryMode) Option {
+	return func(cfg *Config) {
+		cfg.Recovery = mode
+	}
+}
+
+// RecordPanic records the recovered value v as an exception event on span and
+// marks the span as failed. escaped tells whether the panic is resumed.
+func RecordPanic(span trace.Span, v interface{}, escaped bool) {
+	span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+		semconv.ExceptionType(fmt.Sprintf("%T", v)),
+		semconv.ExceptionMessage(fmt.Sprint(v)),
+		semconv.ExceptionStacktrace(string(debug.Stack())),
+		semconv.ExceptionEscaped(escaped),
+	))
+	span.SetStatus(codes.Error, fmt.Sprint(v))
+}
@@ -0,0 +1,202 @@
+package setup
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+	// registers the gzip compressor of the gRPC exporters
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// OTLP protocols, the values of OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http/protobuf"
+)
+
+// otlpConfig holds the OTLP options given to Start, applied to the trace and
+// metric exporters alike. Unset fields are left to the OTEL_EXPORTER_OTLP_*
+// variables.
+type otlpConfig struct {
+	protocol    string
+	endpoint    string
+	insecure    bool
+	tlsConfig   *tls.Config
+	headers     map[string]string
+	compression string
+	timeout     time.Duration
+}
+
+// WithOTLPProtocol selects OTLPProtocolGRPC or OTLPProtocolHTTP, overriding
+// OTEL_EXPORTER_OTLP_PROTOCOL.
+func WithOTLPProtocol(protocol string) Option {
+	return func(cfg *config) {
+		cfg.otlp.protocol = protocol
+	}
+}
+
+// WithOTLPEndpoint sets the host:port of the collector, overriding
+// OTEL_EXPORTER_OTLP_ENDPOINT.
+func WithOTLPEndpoint(endpoint string) Option {
+	return func(cfg *config) {
+		cfg.otlp.endpoint = endpoint
+	}
+}
+
+// WithOTLPInsecure disables TLS towards the collector.
+func WithOTLPInsecure() Option {
+	return func(cfg *config) {
+		cfg.otlp.insecure = true
+	}
+}
+
+// WithOTLPTLSConfig sets the TLS configuration used towards the collector,
+// e.g. to trust a private CA or present a client certificate.
+func WithOTLPTLSConfig(tlsConfig *tls.Config) Option {
+	return func(cfg *config) {
+		cfg.otlp.tlsConfig = tlsConfig
+	}
+}
+
+// WithOTLPHeaders sends headers with every export request, overriding
+// OTEL_EXPORTER_OTLP_HEADERS.
+func WithOTLPHeaders(headers map[string]string) Option {
+	return func(cfg *config) {
+		cfg.otlp.headers = headers
+	}
+}
+
+// WithOTLPCompression sets the compression of export requests, "gzip" or
+// "none", overriding OTEL_EXPORTER_OTLP_COMPRESSION.
+func WithOTLPCompression(compression string) Option {
+	return func(cfg *config) {
+		cfg.otlp.compression = compression
+	}
+}
+
+// WithOTLPTimeout bounds every export request, overriding
+// OTEL_EXPORTER_OTLP_TIMEOUT.
+func WithOTLPTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.otlp.timeout = timeout
+	}
+}
+
+func (c *otlpConfig) validate() error {
+	switch c.compression {
+	case "", "gzip", "none":
+		return nil
+	}
+	return fmt.Errorf("setup: unsupported OTLP compression %q", c.compression)
+}
+
+func (c *otlpConfig) newSpanExporter(ctx context.Context, protocol string) (sdktrace.SpanExporter, error) {
+	switch protocol {
+	case OTLPProtocolGRPC:
+		var opts []otlptracegrpc.Option
+		if c.endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(c.endpoint))
+		}
+		if c.insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConfig)))
+		}
+		if c.headers != nil {
+			opts = append(opts, otlptracegrpc.WithHeaders(c.headers))
+		}
+		if c.compression == "gzip" {
+			opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+		}
+		if c.timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(c.timeout))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case OTLPProtocolHTTP:
+		var opts []otlptracehttp.Option
+		if c.endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.endpoint))
+		}
+		if c.insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(c.tlsConfig))
+		}
+		if c.headers != nil {
+			opts = append(opts, otlptracehttp.WithHeaders(c.headers))
+		}
+		switch c.compression {
+		case "gzip":
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		case "none":
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.NoCompression))
+		}
+		if c.timeout > 0 {
+			opts = append(opts, otlptracehttp.WithTimeout(c.timeout))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("setup: unsupported OTLP protocol %q", protocol)
+}
+
+func (c *otlpConfig) newMetricExporter(ctx context.Context, protocol string) (sdkmetric.Exporter, error) {
+	switch protocol {
+	case OTLPProtocolGRPC:
+		var opts []otlpmetricgrpc.Option
+		if c.endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(c.endpoint))
+		}
+		if c.insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConfig)))
+		}
+		if c.headers != nil {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(c.headers))
+		}
+		if c.compression == "gzip" {
+			opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		if c.timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(c.timeout))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	case OTLPProtocolHTTP:
+		var opts []otlpmetrichttp.Option
+		if c.endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(c.endpoint))
+		}
+		if c.insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if c.tlsConfig != nil {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(c.tlsConfig))
+		}
+		if c.headers != nil {
+			opts = append(opts, otlpmetrichttp.WithHeaders(c.headers))
+		}
+		switch c.compression {
+		case "gzip":
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		case "none":
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.NoCompression))
+		}
+		if c.timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(c.timeout))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("setup: unsupported OTLP protocol %q", protocol)
+}
@@ -0,0 +1,244 @@
+// Package setup configures the OpenTelemetry SDK for the middlewares of this
+// module from the standard OTEL_* environment variables. It lives apart from
+// the otelkit package so the middlewares don't link the SDK and the
+// exporters.
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// Option customizes Start. Options take precedence over the environment.
+type Option func(*config)
+
+type config struct {
+	serviceName string
+	resource    *resource.Resource
+	otlp        otlpConfig
+}
+
+// WithServiceName sets service.name, overriding OTEL_SERVICE_NAME.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithResource merges res into the resource described by the environment,
+// its attributes win.
+func WithResource(res *resource.Resource) Option {
+	return func(cfg *config) {
+		cfg.resource = res
+	}
+}
+
+// Start builds a TracerProvider, a MeterProvider and a propagator from the
+// standard OTEL_* environment variables and registers them globally, where
+// the middlewares of this module find them. The returned shutdown flushes and
+// stops both providers, call it before the process exits.
+//
+// Besides the variables read by the SDK and the exporters themselves, such as
+// OTEL_TRACES_SAMPLER, OTEL_BSP_*, OTEL_METRIC_EXPORT_INTERVAL and
+// OTEL_EXPORTER_OTLP_ENDPOINT, Start understands:
+//
+//   - OTEL_SDK_DISABLED: "true" registers nothing.
+//   - OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES: the resource.
+//   - OTEL_PROPAGATORS: "tracecontext", "baggage" or "none", default
+//     "tracecontext,baggage".
+//   - OTEL_TRACES_EXPORTER: "otlp" or "none", default "otlp".
+//   - OTEL_METRICS_EXPORTER: "otlp", "prometheus" or "none", default "otlp".
+//     The prometheus exporter registers with the default prometheus
+//     registry, serve it with promhttp.Handler.
+//   - OTEL_EXPORTER_OTLP_PROTOCOL, OTEL_EXPORTER_OTLP_TRACES_PROTOCOL and
+//     OTEL_EXPORTER_OTLP_METRICS_PROTOCOL: "grpc" or "http/protobuf", default
+//     "http/protobuf".
+//
+// The OTLP exporters are further configured by the WithOTLP* options. The
+// MeterProvider applies DurationView.
+func Start(ctx context.Context, opts ...Option) (shutdown func(context.Context) error, err error) {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	noop := func(context.Context) error { return nil }
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("OTEL_SDK_DISABLED")), "true") {
+		return noop, nil
+	}
+
+	if err := cfg.otlp.validate(); err != nil {
+		return noop, err
+	}
+
+	propagator, err := propagatorFromEnv()
+	if err != nil {
+		return noop, err
+	}
+
+	res, err := cfg.newResource(ctx)
+	if err != nil {
+		return noop, err
+	}
+
+	// exporters created before a failing one must not leak their connections
+	var created []func(context.Context) error
+	fail := func(err error) (func(context.Context) error, error) {
+		for _, shutdown := range created {
+			_ = shutdown(ctx)
+		}
+		return noop, err
+	}
+
+	tracerOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	for _, name := range envList("OTEL_TRACES_EXPORTER", "otlp") {
+		exporter, err := cfg.newSpanExporter(ctx, name)
+		if err != nil {
+			return fail(err)
+		}
+		if exporter != nil {
+			created = append(created, exporter.Shutdown)
+			tracerOpts = append(tracerOpts, sdktrace.WithBatcher(exporter))
+		}
+	}
+
+	meterOpts := []sdkmetric.Option{sdkmetric.WithResource(res), sdkmetric.WithView(DurationView())}
+	for _, name := range envList("OTEL_METRICS_EXPORTER", "otlp") {
+		reader, err := cfg.newMetricReader(ctx, name)
+		if err != nil {
+			return fail(err)
+		}
+		if reader != nil {
+			created = append(created, reader.Shutdown)
+			meterOpts = append(meterOpts, sdkmetric.WithReader(reader))
+		}
+	}
+
+	tp := sdktrace.NewTracerProvider(tracerOpts...)
+	mp := sdkmetric.NewMeterProvider(meterOpts...)
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(propagator)
+
+	return func(ctx context.Context) error {
+		var msgs []string
+		if err := tp.Shutdown(ctx); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+		if err := mp.Shutdown(ctx); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+		if len(msgs) > 0 {
+			return errors.New(strings.Join(msgs, "; "))
+		}
+		return nil
+	}, nil
+}
+
+func (cfg *config) newResource(ctx context.Context) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	res, err = resource.Merge(resource.Default(), res)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.resource != nil {
+		if res, err = resource.Merge(res, cfg.resource); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.serviceName != "" {
+		res, err = resource.Merge(res, resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.serviceName)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func propagatorFromEnv() (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range envList("OTEL_PROPAGATORS", "tracecontext,baggage") {
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "none":
+		default:
+			return nil, fmt.Errorf("setup: unsupported OTEL_PROPAGATORS value %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+func (cfg *config) newSpanExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "otlp":
+		return cfg.otlp.newSpanExporter(ctx, cfg.otlpProtocol("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"))
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("setup: unsupported OTEL_TRACES_EXPORTER value %q", name)
+}
+
+func (cfg *config) newMetricReader(ctx context.Context, name string) (sdkmetric.Reader, error) {
+	switch name {
+	case "otlp":
+		exporter, err := cfg.otlp.newMetricExporter(ctx, cfg.otlpProtocol("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"))
+		if err != nil {
+			return nil, err
+		}
+		return sdkmetric.NewPeriodicReader(exporter), nil
+	case "prometheus":
+		return prometheus.New()
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("setup: unsupported OTEL_METRICS_EXPORTER value %q", name)
+}
+
+// otlpProtocol returns the protocol given by WithOTLPProtocol, or reads the
+// one of a signal, falling back to OTEL_EXPORTER_OTLP_PROTOCOL.
+func (cfg *config) otlpProtocol(signalKey string) string {
+	if cfg.otlp.protocol != "" {
+		return cfg.otlp.protocol
+	}
+	if v := strings.TrimSpace(os.Getenv(signalKey)); v != "" {
+		return v
+	}
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")); v != "" {
+		return v
+	}
+	return OTLPProtocolHTTP
+}
+
+// envList splits the comma separated list in the environment variable key.
+func envList(key, def string) []string {
+	v := os.Getenv(key)
+	if strings.TrimSpace(v) == "" {
+		v = def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
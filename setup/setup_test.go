@@ -0,0 +1,438 @@
+package setup
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process OTLP receiver remembering the services, spans
+// and metrics exported to it.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	services map[string]struct{}
+	spans    []string
+	metrics  []string
+}
+
+func (c *collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		c.service(rs.Resource)
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.spans = append(c.spans, span.Name)
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *collector) exportMetrics(req *colmetricpb.ExportMetricsServiceRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		c.service(rm.Resource)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				c.metrics = append(c.metrics, m.Name)
+			}
+		}
+	}
+}
+
+func (c *collector) service(res *resourcepb.Resource) {
+	for _, kv := range res.GetAttributes() {
+		if kv.Key == "service.name" {
+			c.services[kv.Value.GetStringValue()] = struct{}{}
+		}
+	}
+}
+
+type metricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	*collector
+}
+
+func (s metricsService) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	s.exportMetrics(req)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+// ServeHTTP receives OTLP over HTTP/protobuf.
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var resp proto.Message
+	switch r.URL.Path {
+	case "/v1/traces":
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = c.Export(r.Context(), req)
+		resp = &coltracepb.ExportTraceServiceResponse{}
+	case "/v1/metrics":
+		req := &colmetricpb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.exportMetrics(req)
+		resp = &colmetricpb.ExportMetricsServiceResponse{}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	b, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+func newCollector() *collector {
+	return &collector{services: map[string]struct{}{}}
+}
+
+// startHTTP serves c over OTLP/HTTP and returns its host:port.
+func (c *collector) startHTTP(t *testing.T) string {
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// startGRPC serves c over OTLP/gRPC and returns its host:port.
+func (c *collector) startGRPC(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, c)
+	colmetricpb.RegisterMetricsServiceServer(srv, metricsService{collector: c})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func (c *collector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans, c.metrics = nil, nil
+}
+
+func (c *collector) received() (services, spans, metrics []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for service := range c.services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services, append([]string(nil), c.spans...), append([]string(nil), c.metrics...)
+}
+
+// setenv clears the variables read by Start, then sets kv pairs, and restores
+// the global providers once t is done.
+func setenv(t *testing.T, kv ...string) {
+	for _, key := range []string{
+		"OTEL_SDK_DISABLED", "OTEL_SERVICE_NAME", "OTEL_RESOURCE_ATTRIBUTES", "OTEL_PROPAGATORS",
+		"OTEL_TRACES_EXPORTER", "OTEL_METRICS_EXPORTER",
+		"OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_METRICS_PROTOCOL",
+		"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT",
+	} {
+		t.Setenv(key, "")
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		t.Setenv(kv[i], kv[i+1])
+	}
+
+	tp, mp, propagator := otel.GetTracerProvider(), otel.GetMeterProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		// the global delegates refuse to be set to themselves
+		if otel.GetTracerProvider() != tp {
+			otel.SetTracerProvider(tp)
+		}
+		if otel.GetMeterProvider() != mp {
+			otel.SetMeterProvider(mp)
+		}
+		if otel.GetTextMapPropagator() != propagator {
+			otel.SetTextMapPropagator(propagator)
+		}
+	})
+}
+
+func TestSetupOTLP(t *testing.T) {
+	for _, protocol := range []string{OTLPProtocolHTTP, OTLPProtocolGRPC} {
+		t.Run(protocol, func(t *testing.T) {
+			setenv(t, "OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+			c := newCollector()
+			endpoint := c.startHTTP(t)
+			if protocol == OTLPProtocolGRPC {
+				endpoint = c.startGRPC(t)
+			}
+
+			ctx := context.Background()
+			shutdown, err := Start(ctx,
+				WithServiceName("checkout"),
+				WithOTLPEndpoint(endpoint),
+				WithOTLPInsecure(),
+				WithOTLPTimeout(5*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, span := otel.Tracer("test").Start(ctx, "work")
+			span.End()
+			counter, _ := otel.Meter("test").Int64Counter("jobs")
+			counter.Add(ctx, 1)
+			if err := shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			services, spans, metrics := c.received()
+			if len(services) != 1 || services[0] != "checkout" {
+				t.Errorf("services = %v, want [checkout]", services)
+			}
+			if len(spans) != 1 || spans[0] != "work" {
+				t.Errorf("spans = %v, want [work]", spans)
+			}
+			if len(metrics) != 1 || metrics[0] != "jobs" {
+				t.Errorf("metrics = %v, want [jobs]", metrics)
+			}
+		})
+	}
+}
+
+func TestSetupExporters(t *testing.T) {
+	c := newCollector()
+	endpoint := c.startHTTP(t)
+
+	for _, tt := range []struct {
+		name            string
+		traces, metrics string
+		spans, points   int
+		err             string
+	}{
+		{"default", "", "", 1, 1, ""},
+		{"none", "none", "none", 0, 0, ""},
+		{"traces only", "otlp", "none", 1, 0, ""},
+		{"duplicate", "otlp, otlp", "otlp", 2, 1, ""},
+		{"unsupported traces", "zipkin", "none", 0, 0, `unsupported OTEL_TRACES_EXPORTER value "zipkin"`},
+		{"unsupported metrics", "none", "logging", 0, 0, `unsupported OTEL_METRICS_EXPORTER value "logging"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t,
+				"OTEL_TRACES_EXPORTER", tt.traces,
+				"OTEL_METRICS_EXPORTER", tt.metrics,
+				"OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+endpoint)
+			c.reset()
+
+			ctx := context.Background()
+			shutdown, err := Start(ctx)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Start() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, span := otel.Tracer("test").Start(ctx, "work")
+			span.End()
+			counter, _ := otel.Meter("test").Int64Counter("jobs")
+			counter.Add(ctx, 1)
+			if err := shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			_, spans, metrics := c.received()
+			if len(spans) != tt.spans || len(metrics) != tt.points {
+				t.Errorf("received %d spans and %d metrics, want %d and %d", len(spans), len(metrics), tt.spans, tt.points)
+			}
+		})
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	setenv(t, "OTEL_SDK_DISABLED", "TRUE", "OTEL_TRACES_EXPORTER", "zipkin")
+	tp := otel.GetTracerProvider()
+
+	shutdown, err := Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() != tp {
+		t.Error("Start registered a TracerProvider")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropagatorFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		env    string
+		fields []string
+		err    bool
+	}{
+		{"", []string{"baggage", "traceparent", "tracestate"}, false},
+		{"tracecontext", []string{"traceparent", "tracestate"}, false},
+		{" Baggage ", []string{"baggage"}, false},
+		{"none", nil, false},
+		{"tracecontext,b3", nil, true},
+	} {
+		t.Run(tt.env, func(t *testing.T) {
+			setenv(t, "OTEL_PROPAGATORS", tt.env)
+			propagator, err := propagatorFromEnv()
+			if (err != nil) != tt.err {
+				t.Fatalf("propagatorFromEnv() error = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			fields := propagator.Fields()
+			sort.Strings(fields)
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+
+	setenv(t, "OTEL_PROPAGATORS", "tracecontext", "OTEL_TRACES_EXPORTER", "none", "OTEL_METRICS_EXPORTER", "none")
+	if _, err := Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fields := otel.GetTextMapPropagator().Fields(); len(fields) != 2 {
+		t.Errorf("registered propagator fields = %v, want the tracecontext ones", fields)
+	}
+}
+
+func TestOTLPProtocol(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		option          string
+		env             []string
+		traces, metrics string
+	}{
+		{"default", "", nil, OTLPProtocolHTTP, OTLPProtocolHTTP},
+		{"general", "", []string{"OTEL_EXPORTER_OTLP_PROTOCOL", "grpc"}, OTLPProtocolGRPC, OTLPProtocolGRPC},
+		{"signal wins over general", "", []string{
+			"OTEL_EXPORTER_OTLP_PROTOCOL", "grpc",
+			"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/protobuf",
+		}, OTLPProtocolHTTP, OTLPProtocolGRPC},
+		{"option wins over environment", OTLPProtocolGRPC, []string{
+			"OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf",
+			"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", "http/protobuf",
+		}, OTLPProtocolGRPC, OTLPProtocolGRPC},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env...)
+			cfg := &config{}
+			if tt.option != "" {
+				WithOTLPProtocol(tt.option)(cfg)
+			}
+			if got := cfg.otlpProtocol("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"); got != tt.traces {
+				t.Errorf("traces protocol = %q, want %q", got, tt.traces)
+			}
+			if got := cfg.otlpProtocol("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"); got != tt.metrics {
+				t.Errorf("metrics protocol = %q, want %q", got, tt.metrics)
+			}
+		})
+	}
+
+	setenv(t, "OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := Start(context.Background()); err == nil || !strings.Contains(err.Error(), `unsupported OTLP protocol "http/json"`) {
+		t.Errorf("Start() error = %v, want unsupported protocol", err)
+	}
+}
+
+// countingListener tracks the connections accepted and still open.
+type countingListener struct {
+	net.Listener
+	mu             sync.Mutex
+	accepted, open int
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.accepted++
+	l.open++
+	l.mu.Unlock()
+	return &countingConn{Conn: conn, l: l}, nil
+}
+
+func (l *countingListener) counts() (accepted, open int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.accepted, l.open
+}
+
+type countingConn struct {
+	net.Conn
+	l    *countingListener
+	once sync.Once
+}
+
+func (c *countingConn) Close() error {
+	c.once.Do(func() {
+		c.l.mu.Lock()
+		c.l.open--
+		c.l.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func TestSetupShutdownOnFailure(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingListener{Listener: lis}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, newCollector())
+	go func() { _ = srv.Serve(counting) }()
+	defer srv.Stop()
+
+	// the trace exporter connects to the collector before the metric exporter
+	// turns out to be unsupported
+	setenv(t, "OTEL_METRICS_EXPORTER", "logging")
+	_, err = Start(context.Background(),
+		WithOTLPProtocol(OTLPProtocolGRPC),
+		WithOTLPEndpoint(lis.Addr().String()),
+		WithOTLPInsecure())
+	if err == nil {
+		t.Fatal("Start() succeeded with an unsupported metrics exporter")
+	}
+
+	// a leaked exporter keeps dialing the collector in the background, a shut
+	// down one closes its connection, if it even got to open one
+	time.Sleep(500 * time.Millisecond)
+	if accepted, open := counting.counts(); open != 0 {
+		t.Errorf("%d connections accepted, %d still open", accepted, open)
+	}
+}
@@ -0,0 +1,36 @@
+package setup
+
+import (
+	"strings"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/aggregation"
+)
+
+// DurationBuckets are the bucket boundaries, in seconds, recommended by the
+// OpenTelemetry semantic conventions for HTTP request durations.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// scopePrefix prefixes the instrumentation scope of every package in this
+// module.
+const scopePrefix = "github.com/nnnewb/otelkit/"
+
+// DurationView applies DurationBuckets to the duration histograms, measured
+// in seconds, of this module. The metric API offers no way for
+// instrumentation to hint bucket boundaries, register the view with the
+// MeterProvider:
+//
+//	provider := metric.NewMeterProvider(metric.WithReader(reader), metric.WithView(setup.DurationView()))
+func DurationView() sdkmetric.View {
+	return func(i sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		if i.Kind != sdkmetric.InstrumentKindHistogram || i.Unit != "s" || !strings.HasPrefix(i.Scope.Name, scopePrefix) {
+			return sdkmetric.Stream{}, false
+		}
+		return sdkmetric.Stream{
+			Name:        i.Name,
+			Description: i.Description,
+			Unit:        i.Unit,
+			Aggregation: aggregation.ExplicitBucketHistogram{Boundaries: DurationBuckets},
+		}, true
+	}
+}
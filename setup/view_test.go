@@ -0,0 +1,70 @@
+package setup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	metrichttp "github.com/nnnewb/otelkit/metric/http"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDurationView(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithView(DurationView()))
+	defer provider.Shutdown(context.Background())
+
+	handler := metrichttp.NewMeasureHandler(otelkit.WithMeterProvider(provider))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	record := func(scope, name, unit string) {
+		h, err := provider.Meter(scope).Float64Histogram(name, metric.WithUnit(unit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Record(context.Background(), 1)
+	}
+	// this module's durations in milliseconds, such as rpc.server.duration
+	record(scopePrefix+"metric/kit/grpc", "rpc.server.duration", "ms")
+	// another library's durations in seconds
+	record("example.com/other", "other.duration", "s")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	bounds := map[string][]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				bounds[m.Name] = data.DataPoints[0].Bounds
+			case metricdata.Histogram[int64]:
+				bounds[m.Name] = data.DataPoints[0].Bounds
+			}
+		}
+	}
+
+	for name, want := range map[string]bool{
+		"http.server.request.duration": true,
+		// this module's histograms of other units, in bytes here
+		"http.server.request.body.size": false,
+		"rpc.server.duration":           false,
+		"other.duration":                false,
+	} {
+		got, ok := bounds[name]
+		if !ok {
+			t.Errorf("%s not recorded", name)
+			continue
+		}
+		if reflect.DeepEqual(got, DurationBuckets) != want {
+			t.Errorf("%s bounds = %v, DurationBuckets applied should be %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package otelkit
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SpanNameFormatter names the span of req. route is the route template the
+// request matched, such as "/users/:id", or the operation name given by
+// WithOperationName. It is empty when neither is known.
+type SpanNameFormatter func(route string, req *http.Request) string
+
+// DefaultSpanNameFormatter names spans "METHOD /route" when route is a path,
+// uses route verbatim when it is an operation name, and falls back to the bare
+// method so raw URLs never end up in span names.
+func DefaultSpanNameFormatter(route string, req *http.Request) string {
+	switch {
+	case route == "":
+		return req.Method
+	case strings.HasPrefix(route, "/"):
+		return req.Method + " " + route
+	default:
+		return route
+	}
+}
+
+// WithSpanNameFormatter overrides how spans are named.
+func WithSpanNameFormatter(formatter SpanNameFormatter) Option {
+	return func(cfg *Config) {
+		cfg.SpanNameFormatter = formatter
+	}
+}
+
+// WithOperationName sets the operation name of middlewares which cannot learn
+// a route template from their framework, such as go-kit servers.
+func WithOperationName(name string) Option {
+	return func(cfg *Config) {
+		cfg.OperationName = name
+	}
+}
+
+// SpanName names the span of req with the configured SpanNameFormatter.
+func (c *Config) SpanName(route string, req *http.Request) string {
+	if c.SpanNameFormatter != nil {
+		return c.SpanNameFormatter(route, req)
+	}
+	return DefaultSpanNameFormatter(route, req)
+}
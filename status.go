@@ -0,0 +1,50 @@
+package otelkit
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StatusClassifier decides the span status from the HTTP status code of a
+// response and the error that occurred while producing it, if any. status is
+// zero when no response is available.
+type StatusClassifier func(status int, err error) (codes.Code, string)
+
+// ServerStatus is the default StatusClassifier of server spans. Following the
+// OpenTelemetry HTTP semantic conventions, 5xx responses and errors mark the
+// span as failed, 4xx responses do not.
+func ServerStatus(status int, err error) (codes.Code, string) {
+	if err != nil {
+		return codes.Error, err.Error()
+	}
+	if status == 0 {
+		return codes.Unset, ""
+	}
+	if status < 100 || status >= 500 {
+		return codes.Error, ""
+	}
+	return codes.Unset, ""
+}
+
+// ClientStatus is the default StatusClassifier of client spans. Any 4xx or
+// 5xx response and any error marks the span as failed.
+func ClientStatus(status int, err error) (codes.Code, string) {
+	if err != nil {
+		return codes.Error, err.Error()
+	}
+	if status == 0 {
+		return codes.Unset, ""
+	}
+	if status < 100 || status >= 400 {
+		return codes.Error, ""
+	}
+	return codes.Unset, ""
+}
+
+// SetSpanStatus classifies status and err and applies the result to span.
+func SetSpanStatus(span trace.Span, classifier StatusClassifier, status int, err error) {
+	code, description := classifier(status, err)
+	if code != codes.Unset {
+		span.SetStatus(code, description)
+	}
+}
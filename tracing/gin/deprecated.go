@@ -0,0 +1,16 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceMiddleware traces the requests handled by gin with tracer.
+//
+// Deprecated: use NewTraceMiddleware(otelkit.WithTracer(tracer),
+// otelkit.WithPropagators(propagator)).
+func TraceMiddleware(tracer trace.Tracer, propagator propagation.TextMapPropagator) gin.HandlerFunc {
+	return NewTraceMiddleware(otelkit.WithTracer(tracer), otelkit.WithPropagators(propagator))
+}
@@ -1,44 +1,129 @@
 package gin
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.opentelemetry.io/otel/attribute"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/ginconv"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func TraceMiddleware(tracer trace.Tracer, propagator propagation.TextMapPropagator) gin.HandlerFunc {
+// ScopeName is the instrumentation scope of the spans created by this package.
+const ScopeName = "github.com/nnnewb/otelkit/tracing/gin"
+
+// spanKey stores the server span in gin.Context, which only takes string keys.
+const spanKey = ScopeName + ".span"
+
+// NewTraceMiddleware returns a gin middleware starting a server span for every
+// request, child of the trace context propagated by the client. The span is
+// named after the route template, or otelkit.WithOperationName for requests
+// matching no route. Handlers find it with SpanFromGinContext.
+func NewTraceMiddleware(opts ...otelkit.Option) gin.HandlerFunc {
+	cfg := otelkit.NewConfig(opts...)
+	tracer := cfg.Tracer(ScopeName)
+	classifier := cfg.Classifier(otelkit.ServerStatus)
 	return func(c *gin.Context) {
 		req := c.Request
-		ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
-		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, req.URL.String()))
-		defer span.End()
+		if !cfg.Instrumented(req) {
+			c.Next()
+			return
+		}
+
+		ctx := cfg.Propagators.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = cfg.OperationName
+		}
+		ctx, span := tracer.Start(ctx, cfg.SpanName(route, req),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(cfg.Attributes...))
+		panicked := true
 		defer func() {
+			var recovered interface{}
+			if panicked && cfg.Recovery != otelkit.RecoveryDisabled {
+				recovered = recover()
+			}
+			repanic := recovered != nil && (cfg.Recovery == otelkit.RecoveryRepanic || recovered == http.ErrAbortHandler)
 			wr := c.Writer
-			var attrs = make([]attribute.KeyValue, 0, len(wr.Header()))
-			for key, values := range wr.Header() {
-				attrs = append(attrs, attribute.String("http.response.header."+key, strings.Join(values, "\n")))
+			if recovered != nil {
+				otelkit.RecordPanic(span, recovered, repanic)
+				if !wr.Written() && !repanic {
+					c.AbortWithStatus(http.StatusInternalServerError)
+				}
+			}
+
+			// a panicking request is a 500 whatever got written, as in the
+			// metrics, even when it is not recovered
+			status := wr.Status()
+			if panicked {
+				status = http.StatusInternalServerError
+			}
+
+			// errors pushed by handlers with c.Error
+			for _, err := range c.Errors {
+				span.RecordError(err.Err, trace.WithAttributes(ginconv.ErrorTypeKey.String(ginconv.ErrorType(err))))
+			}
+
+			span.SetAttributes(cfg.ResponseHeaderAttributes(wr.Header())...)
+			span.SetAttributes(httpconv.ServerResponse(status, int64(wr.Size()))...)
+			switch {
+			case recovered != nil:
+				// RecordPanic set the status
+			case panicked:
+				span.SetStatus(codes.Error, "handler panicked")
+			default:
+				// the response status tells whether the server failed, a 4xx
+				// answering a c.Error is the client's fault. gin flushes a
+				// status set with c.Status only after the handlers ran.
+				answered := wr.Written() || status != http.StatusOK
+				var err error
+				if last := c.Errors.Last(); last != nil && (!answered || status >= http.StatusInternalServerError) {
+					err = last
+				}
+				otelkit.SetSpanStatus(span, classifier, status, err)
+			}
+
+			// ended here rather than deferred, which would have the SDK record
+			// a panic going through a second time
+			span.End()
+			if repanic {
+				panic(recovered)
 			}
-			span.SetAttributes(attrs...)
 		}()
 
-		var attrs []attribute.KeyValue
-		for key, values := range req.Header {
-			attrs = append(attrs, attribute.String("http.request.header."+key, strings.Join(values, "\n")))
-		}
-		span.SetAttributes(
-			attribute.Int64("http.request_content_length", req.ContentLength),
-			attribute.String("http.method", req.Method),
-			attribute.String("net.protocol.name", "http"),
-			attribute.String("net.protocol.version", req.Proto),
-			attribute.String("net.sock.peer.addr", req.RemoteAddr),
-			attribute.String("user_agent.original", req.Header.Get("User-Agent")))
-		span.SetAttributes(attrs...)
-		req = req.WithContext(ctx)
-		c.Set("span", span)
+		span.SetAttributes(httpconv.ServerRequest(req)...)
+		span.SetAttributes(cfg.RequestHeaderAttributes(req.Header)...)
+		span.SetAttributes(httpconv.ServerRoute(route)...)
+		c.Request = req.WithContext(ctx)
+		c.Set(spanKey, span)
 		c.Next()
+		panicked = false
+	}
+}
+
+// SpanFromGinContext returns the server span started by NewTraceMiddleware for
+// c, or the span of the request context when the middleware didn't trace c.
+// The returned span is never nil.
+func SpanFromGinContext(c *gin.Context) trace.Span {
+	if v, ok := c.Get(spanKey); ok {
+		if span, ok := v.(trace.Span); ok {
+			return span
+		}
+	}
+	return trace.SpanFromContext(ContextFromGin(c))
+}
+
+// ContextFromGin returns the request context of c, carrying the server span
+// started by NewTraceMiddleware. Use it for outgoing calls so they join the
+// trace.
+func ContextFromGin(c *gin.Context) context.Context {
+	if c.Request == nil {
+		return context.Background()
 	}
+	return c.Request.Context()
 }
@@ -0,0 +1,198 @@
+package gin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracegin "github.com/nnnewb/otelkit/tracing/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(tracegin.NewTraceMiddleware(h.Options()...))
+	r.GET("/users/:id", func(c *gin.Context) {
+		if tracegin.SpanFromGinContext(c) == nil {
+			t.Error("SpanFromGinContext returned nil")
+		}
+		c.Status(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	h.RequireSpanCount(t, 1)
+	span := h.RequireSpan(t, trace.SpanKindServer, "GET /users/:id",
+		attribute.String("http.route", "/users/:id"),
+		attribute.Int("http.status_code", http.StatusNotFound))
+	if span.Status().Code != codes.Unset {
+		t.Errorf("status = %v, want unset for a server 4xx", span.Status().Code)
+	}
+}
+
+func TestTraceMiddlewareErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler gin.HandlerFunc
+		status  int
+		code    codes.Code
+	}{
+		{"server error", func(c *gin.Context) {
+			_ = c.Error(errors.New("bad input")).SetType(gin.ErrorTypeBind)
+			_ = c.Error(errors.New("db down"))
+			c.Status(http.StatusInternalServerError)
+		}, http.StatusInternalServerError, codes.Error},
+		{"bind failure", func(c *gin.Context) {
+			var body struct {
+				Name string `json:"name" binding:"required"`
+			}
+			_ = c.Error(errors.New("bad input")).SetType(gin.ErrorTypeBind)
+			_ = c.Bind(&body)
+		}, http.StatusBadRequest, codes.Unset},
+		{"not found", func(c *gin.Context) {
+			_ = c.Error(errors.New("no such user"))
+			_ = c.Error(errors.New("not cached"))
+			c.Status(http.StatusNotFound)
+		}, http.StatusNotFound, codes.Unset},
+		{"unanswered", func(c *gin.Context) {
+			_ = c.Error(errors.New("db down"))
+			_ = c.Error(errors.New("cache down"))
+		}, http.StatusOK, codes.Error},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			r := gin.New()
+			r.Use(tracegin.NewTraceMiddleware(h.Options()...))
+			r.POST("/fail", tt.handler)
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", strings.NewReader("{}")))
+
+			span := h.RequireSpan(t, trace.SpanKindServer, "POST /fail",
+				attribute.Int("http.status_code", tt.status))
+			if span.Status().Code != tt.code {
+				t.Errorf("status = %v, want %v", span.Status().Code, tt.code)
+			}
+			var exceptions int
+			for _, event := range span.Events() {
+				if event.Name == "exception" {
+					exceptions++
+				}
+			}
+			if exceptions != 2 {
+				t.Errorf("recorded %d errors, want 2", exceptions)
+			}
+		})
+	}
+}
+
+func TestTraceMiddlewareUnmatched(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(tracegin.NewTraceMiddleware(h.Options(otelkit.WithOperationName("unmatched"))...))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	h.RequireSpan(t, trace.SpanKindServer, "unmatched",
+		attribute.Int("http.status_code", http.StatusNotFound))
+}
+
+func TestTraceMiddlewarePanic(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(tracegin.NewTraceMiddleware(h.Options(otelkit.WithPanicRecovery(otelkit.RecoveryRespond))...))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("response code = %d, want 500", rec.Code)
+	}
+	span := h.RequireSpan(t, trace.SpanKindServer, "GET /panic",
+		attribute.Int("http.status_code", http.StatusInternalServerError))
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", span.Status().Code)
+	}
+}
+
+func TestTraceMiddlewarePanicResumed(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		mode   otelkit.RecoveryMode
+		events int
+	}{
+		// left alone, the panic value is out of reach
+		{"disabled", otelkit.RecoveryDisabled, 0},
+		{"repanic", otelkit.RecoveryRepanic, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			r := gin.New()
+			r.Use(tracegin.NewTraceMiddleware(h.Options(otelkit.WithPanicRecovery(tt.mode))...))
+			r.GET("/panic", func(c *gin.Context) {
+				panic("boom")
+			})
+
+			func() {
+				defer func() {
+					if v := recover(); v != "boom" {
+						t.Errorf("recovered %v, want boom", v)
+					}
+				}()
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+			}()
+
+			span := h.RequireSpan(t, trace.SpanKindServer, "GET /panic",
+				attribute.Int("http.status_code", http.StatusInternalServerError))
+			if span.Status().Code != codes.Error {
+				t.Errorf("status = %v, want error", span.Status().Code)
+			}
+			if events := span.Events(); len(events) != tt.events {
+				t.Errorf("got %d events, want %d", len(events), tt.events)
+			}
+		})
+	}
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(tracegin.NewTraceMiddleware(h.Options()...))
+	r.GET("/users/:id", func(c *gin.Context) {
+		_ = c.Error(errors.New("no such user")).SetType(gin.ErrorTypePublic)
+		c.Status(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("User-Agent", "otelkittest")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	h.RequireGolden(t, "golden")
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	r := gin.New()
+	r.Use(tracegin.TraceMiddleware(h.TracerProvider.Tracer("legacy"), propagation.TraceContext{}))
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	h.RequireSpan(t, trace.SpanKindServer, "GET /users/:id",
+		attribute.Int("http.status_code", http.StatusNoContent))
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHandler traces the requests served by next with tracer.
+//
+// Deprecated: use NewTraceHandler(otelkit.WithTracer(tracer),
+// otelkit.WithPropagators(propagator)).
+func TraceHandler(tracer trace.Tracer, propagator propagation.TextMapPropagator) func(next http.Handler) http.Handler {
+	return NewTraceHandler(otelkit.WithTracer(tracer), otelkit.WithPropagators(propagator))
+}
+
+// TraceRequest injects the trace context of ctx into req.
+//
+// Deprecated: use TraceClientRequest(ctx, req,
+// otelkit.WithPropagators(propagator)).
+func TraceRequest(ctx context.Context, propagator propagation.TextMapPropagator, req *http.Request) {
+	TraceClientRequest(ctx, req, otelkit.WithPropagators(propagator))
+}
@@ -7,51 +7,31 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
+	"github.com/nnnewb/otelkit/setup"
 	http2 "github.com/nnnewb/otelkit/tracing/http"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/baggage"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	tracesdk "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-const (
-	url     = "http://192.168.56.4:14268/api/traces"
-	service = "http-client-app"
-)
+const service = "http-client-app"
 
 func main() {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
-
-	tp := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
-		// Record information about this application in a Resource.
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(service),
-		)),
-	)
 	defer func() {
-		err := tp.Shutdown(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
 		if err != nil {
-			log.Printf("shutdown failed, error %+v", err)
+			log.Printf("shutdown returned error %+v", err)
 		}
 	}()
 
-	tr := tp.Tracer(service)
+	tr := otel.Tracer(service)
 
 	request, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:9998/hello", nil)
 	if err != nil {
@@ -62,7 +42,6 @@ func main() {
 	defer cancel()
 	ctx, span := tr.Start(ctx, fmt.Sprintf("client %s %s", request.Method, request.URL.String()))
 	defer span.End()
-	request = request.WithContext(ctx)
 
 	b, err := baggage.New()
 	if err != nil {
@@ -81,18 +60,17 @@ func main() {
 
 	ctx = baggage.ContextWithBaggage(ctx, b)
 
-	// request.Header = http.Header{}
-	http2.TraceRequest(ctx, otel.GetTextMapPropagator(), request)
-	response, err := http.DefaultClient.Do(request)
+	client := &http.Client{
+		Transport: http2.TraceTransport()(http.DefaultTransport),
+	}
+	response, err := client.Do(request.WithContext(ctx))
 	if err != nil {
 		panic(err)
 	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(response.Body)
 
-	var attrs = make([]attribute.KeyValue, 0, len(response.Header))
-	for key, values := range response.Header {
-		attrs = append(attrs, attribute.String("http.response.header."+key, strings.Join(values, "\n")))
-	}
-	span.SetAttributes(attrs...)
 	_, err = io.Copy(os.Stdout, response.Body)
 	if err != nil {
 		panic(err)
@@ -1,49 +1,46 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/filters"
+	"github.com/nnnewb/otelkit/setup"
 	http2 "github.com/nnnewb/otelkit/tracing/http"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	tracesdk "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-const (
-	url     = "http://192.168.56.4:14268/api/traces"
-	service = "http-app"
-)
+const service = "http-app"
 
 func main() {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
-
-	tp := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
-		// Record information about this application in a Resource.
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(service),
-		)),
-	)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
+		if err != nil {
+			log.Printf("shutdown returned error %+v", err)
+		}
+	}()
 
 	var handler = http.Handler(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
 		_, _ = wr.Write([]byte("Hello world!"))
 	}))
-	handler = http2.TraceHandler(tp.Tracer("http-example"), otel.GetTextMapPropagator())(handler)
 	http.DefaultServeMux.Handle("/hello", handler)
+	http.DefaultServeMux.HandleFunc("/healthz", func(wr http.ResponseWriter, req *http.Request) {
+		wr.WriteHeader(http.StatusNoContent)
+	})
+	traced := http2.NewTraceHandler(
+		// probes are not worth a span
+		otelkit.WithFilter(filters.Not(filters.Path("/healthz"))),
+	)(http.DefaultServeMux)
 	log.Println("server start listen at http://127.0.0.1:9998")
-	err = http.ListenAndServe("127.0.0.1:9998", http.DefaultServeMux)
+	err = http.ListenAndServe("127.0.0.1:9998", traced)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,72 @@
+//go:build go1.22
+
+//go:debug httpmuxgo121=0
+
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metrichttp "github.com/nnnewb/otelkit/metric/http"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracehttp "github.com/nnnewb/otelkit/tracing/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestTraceHandlerServeMux(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := tracehttp.NewTraceHandler(h.Options()...)(newMux())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	h.RequireSpan(t, trace.SpanKindServer, "GET /users/{id}",
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int("http.status_code", http.StatusNoContent))
+	h.RequireSpan(t, trace.SpanKindServer, "GET",
+		attribute.Int("http.status_code", http.StatusNotFound))
+}
+
+// The route must reach both middlewares whichever wraps the other, although
+// NewTraceHandler hands a copy of the request to the ServeMux.
+func TestServeMuxStacking(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		stack func(h *otelkittest.Harness, mux http.Handler) http.Handler
+	}{
+		{"measure(trace(mux))", func(h *otelkittest.Harness, mux http.Handler) http.Handler {
+			return metrichttp.NewMeasureHandler(h.Options()...)(tracehttp.NewTraceHandler(h.Options()...)(mux))
+		}},
+		{"trace(measure(mux))", func(h *otelkittest.Harness, mux http.Handler) http.Handler {
+			return tracehttp.NewTraceHandler(h.Options()...)(metrichttp.NewMeasureHandler(h.Options()...)(mux))
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			handler := tt.stack(h, newMux())
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+			h.RequireSpan(t, trace.SpanKindServer, "GET /users/{id}",
+				attribute.String("http.route", "/users/{id}"))
+			h.RequireHistogram(t, "http.server.request.duration", 1,
+				attribute.String("http.route", "/users/{id}"),
+				attribute.Int("http.response.status_code", http.StatusNoContent))
+			h.RequireHistogram(t, "http.server.request.duration", 1,
+				attribute.String("http.route", "_OTHER"),
+				attribute.Int("http.response.status_code", http.StatusNotFound))
+		})
+	}
+}
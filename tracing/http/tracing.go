@@ -2,62 +2,119 @@ package http
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"strings"
 
-	"go.opentelemetry.io/otel/attribute"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"github.com/nnnewb/otelkit/internal/respwriter"
+	"github.com/nnnewb/otelkit/internal/route"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func TraceHandler(tracer trace.Tracer, propagator propagation.TextMapPropagator) func(next http.Handler) http.Handler {
+// ScopeName is the instrumentation scope of the spans created by this package.
+const ScopeName = "github.com/nnnewb/otelkit/tracing/http"
+
+// NewTraceHandler returns a middleware starting a server span for every
+// request served by next, child of the trace context propagated by the
+// client. The span is named after the ServeMux pattern the request matched,
+// or otelkit.WithOperationName, and records the response status and size.
+func NewTraceHandler(opts ...otelkit.Option) func(next http.Handler) http.Handler {
+	cfg := otelkit.NewConfig(opts...)
+	tracer := cfg.Tracer(ScopeName)
+	classifier := cfg.Classifier(otelkit.ServerStatus)
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
-			ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
-			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, req.URL.String()))
-			defer span.End()
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !cfg.Instrumented(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			wr, w := respwriter.Wrap(w)
+			caller := req
+			ctx := cfg.Propagators.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracer.Start(ctx, spanName(cfg, req),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(cfg.Attributes...))
+			panicked := true
 			defer func() {
-				var attrs = make([]attribute.KeyValue, 0, len(wr.Header()))
-				for key, values := range wr.Header() {
-					attrs = append(attrs, attribute.String("http.response.header."+key, strings.Join(values, "\n")))
+				var recovered interface{}
+				if panicked && cfg.Recovery != otelkit.RecoveryDisabled {
+					recovered = recover()
+				}
+				repanic := recovered != nil && (cfg.Recovery == otelkit.RecoveryRepanic || recovered == http.ErrAbortHandler)
+				if recovered != nil {
+					otelkit.RecordPanic(span, recovered, repanic)
+					if !wr.Written() && !repanic {
+						wr.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+
+				// the ServeMux behind us fills in the matched pattern only once it
+				// routed the request, and only on our copy of it.
+				route.Propagate(caller, req)
+				r := route.Pattern(req)
+				if r != "" {
+					span.SetName(cfg.SpanName(r, req))
+				} else {
+					r = cfg.OperationName
+				}
+				span.SetAttributes(httpconv.ServerRoute(r)...)
+				// a panicking request is a 500 whatever got written, as in the
+				// metrics, even when it is not recovered
+				status := wr.Status()
+				if panicked {
+					status = http.StatusInternalServerError
+				}
+				span.SetAttributes(cfg.ResponseHeaderAttributes(wr.Header())...)
+				span.SetAttributes(httpconv.ServerResponse(status, wr.Size())...)
+				switch {
+				case recovered != nil:
+					// RecordPanic set the status
+				case panicked:
+					span.SetStatus(codes.Error, "handler panicked")
+				default:
+					otelkit.SetSpanStatus(span, classifier, status, nil)
+				}
+
+				// ended here rather than deferred, which would have the SDK record
+				// a panic going through a second time
+				span.End()
+				if repanic {
+					panic(recovered)
 				}
-				span.SetAttributes(attrs...)
 			}()
 
-			var attrs []attribute.KeyValue
-			for key, values := range req.Header {
-				attrs = append(attrs, attribute.String("http.request.header."+key, strings.Join(values, "\n")))
-			}
-			span.SetAttributes(
-				attribute.Int64("http.request_content_length", req.ContentLength),
-				attribute.String("http.method", req.Method),
-				attribute.String("net.protocol.name", "http"),
-				attribute.String("net.protocol.version", req.Proto),
-				attribute.String("net.sock.peer.addr", req.RemoteAddr),
-				attribute.String("user_agent.original", req.Header.Get("User-Agent")))
-			span.SetAttributes(attrs...)
+			span.SetAttributes(httpconv.ServerRequest(req)...)
+			span.SetAttributes(cfg.RequestHeaderAttributes(req.Header)...)
 			req = req.WithContext(ctx)
-			next.ServeHTTP(wr, req)
+			next.ServeHTTP(w, req)
+			panicked = false
 		})
 	}
 }
 
-func TraceRequest(ctx context.Context, propagator propagation.TextMapPropagator, req *http.Request) {
-	injectHttpHeader(ctx, propagator, req.Header)
-	span := trace.SpanFromContext(ctx)
-	var attrs []attribute.KeyValue
-	for key, values := range req.Header {
-		attrs = append(attrs, attribute.String("http.request.header."+key, strings.Join(values, "\n")))
+func spanName(cfg *otelkit.Config, req *http.Request) string {
+	if r := route.Pattern(req); r != "" {
+		return cfg.SpanName(r, req)
 	}
-	span.SetAttributes(
-		attribute.Int64("http.request_content_length", req.ContentLength),
-		attribute.String("http.method", req.Method),
-		attribute.String("net.protocol.name", "http"),
-		attribute.String("net.protocol.version", req.Proto),
-		attribute.String("net.sock.peer.addr", req.RemoteAddr),
-		attribute.String("user_agent.original", req.Header.Get("User-Agent")))
-	span.SetAttributes(attrs...)
+	return cfg.SpanName(cfg.OperationName, req)
+}
+
+// TraceClientRequest injects the trace context of ctx into the headers of req
+// and records the request attributes on the span of ctx. Use TraceTransport
+// to start a client span per request instead.
+func TraceClientRequest(ctx context.Context, req *http.Request, opts ...otelkit.Option) {
+	cfg := otelkit.NewConfig(opts...)
+	if !cfg.Instrumented(req) {
+		return
+	}
+	injectHttpHeader(ctx, cfg.Propagators, req.Header)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(cfg.Attributes...)
+	span.SetAttributes(httpconv.ClientRequest(req)...)
+	span.SetAttributes(cfg.RequestHeaderAttributes(req.Header)...)
 }
 
 func injectHttpHeader(ctx context.Context, propagator propagation.TextMapPropagator, header http.Header) {
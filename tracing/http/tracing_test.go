@@ -0,0 +1,185 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracehttp "github.com/nnnewb/otelkit/tracing/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceHandler(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := tracehttp.NewTraceHandler(h.Options(otelkit.WithOperationName("/users"))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users?id=1", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	h.RequireSpanCount(t, 1)
+	span := h.RequireSpan(t, trace.SpanKindServer, "GET /users",
+		attribute.String("http.method", http.MethodGet),
+		attribute.Int("http.status_code", http.StatusNotFound))
+	if span.Status().Code != codes.Unset {
+		t.Errorf("status = %v, want unset for a server 4xx", span.Status().Code)
+	}
+}
+
+func TestTraceHandlerServerError(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := tracehttp.NewTraceHandler(h.Options()...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	span := h.RequireSpan(t, trace.SpanKindServer, "POST",
+		attribute.Int("http.status_code", http.StatusServiceUnavailable))
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", span.Status().Code)
+	}
+}
+
+func TestTraceHandlerPanic(t *testing.T) {
+	h := otelkittest.New(t)
+	handler := tracehttp.NewTraceHandler(h.Options(otelkit.WithPanicRecovery(otelkit.RecoveryRespond))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("response code = %d, want 500", rec.Code)
+	}
+	span := h.RequireSpan(t, trace.SpanKindServer, "GET",
+		attribute.Int("http.status_code", http.StatusInternalServerError))
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", span.Status().Code)
+	}
+}
+
+func TestTraceHandlerPanicResumed(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		mode   otelkit.RecoveryMode
+		events int
+	}{
+		// left alone, the panic value is out of reach
+		{"disabled", otelkit.RecoveryDisabled, 0},
+		{"repanic", otelkit.RecoveryRepanic, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			handler := tracehttp.NewTraceHandler(h.Options(otelkit.WithPanicRecovery(tt.mode))...)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					panic("boom")
+				}))
+
+			func() {
+				defer func() {
+					if v := recover(); v != "boom" {
+						t.Errorf("recovered %v, want boom", v)
+					}
+				}()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+
+			span := h.RequireSpan(t, trace.SpanKindServer, "GET",
+				attribute.Int("http.status_code", http.StatusInternalServerError))
+			if span.Status().Code != codes.Error {
+				t.Errorf("status = %v, want error", span.Status().Code)
+			}
+			if events := span.Events(); len(events) != tt.events {
+				t.Errorf("got %d events, want %d", len(events), tt.events)
+			}
+		})
+	}
+}
+
+func TestTraceHandlerFiltered(t *testing.T) {
+	h := otelkittest.New(t)
+	skip := func(*http.Request) bool { return false }
+	handler := tracehttp.NewTraceHandler(h.Options(otelkit.WithFilter(skip))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireSpanCount(t, 0)
+}
+
+func TestTraceTransportPropagates(t *testing.T) {
+	h := otelkittest.New(t)
+	var parent trace.SpanContext
+	srv := httptest.NewServer(tracehttp.NewTraceHandler(h.Options()...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent = trace.SpanContextFromContext(r.Context())
+		})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: tracehttp.TraceTransport(h.Options()...)(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	clientSpan := h.RequireSpan(t, trace.SpanKindClient, "GET",
+		attribute.Int("http.status_code", http.StatusOK))
+	serverSpan := h.RequireSpan(t, trace.SpanKindServer, "GET")
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Errorf("server span parent = %s, want client span %s",
+			serverSpan.Parent().SpanID(), clientSpan.SpanContext().SpanID())
+	}
+	if parent.TraceID() != clientSpan.SpanContext().TraceID() {
+		t.Errorf("handler trace = %s, want %s", parent.TraceID(), clientSpan.SpanContext().TraceID())
+	}
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(tracehttp.NewTraceHandler(h.Options(otelkit.WithOperationName("/users"))...)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: tracehttp.TraceTransport(h.Options()...)(nil)}
+	resp, err := client.Get(srv.URL + "/users?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	h.RequireGolden(t, "golden")
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	tracer := h.TracerProvider.Tracer("legacy")
+	handler := tracehttp.TraceHandler(tracer, propagation.TraceContext{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	h.RequireSpan(t, trace.SpanKindServer, "GET", attribute.Int("http.status_code", http.StatusOK))
+
+	ctx, span := tracer.Start(context.Background(), "call")
+	defer span.End()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	tracehttp.TraceRequest(ctx, propagation.TraceContext{}, req)
+	if req.Header.Get("Traceparent") == "" {
+		t.Error("TraceRequest did not inject the trace context")
+	}
+}
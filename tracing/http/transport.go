@@ -0,0 +1,128 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceTransport wraps an http.RoundTripper so that every outbound request is
+// traced by a client span. The span ends when the response body is closed or
+// fully read, or immediately when the round trip fails.
+func TraceTransport(opts ...otelkit.Option) func(next http.RoundTripper) http.RoundTripper {
+	cfg := otelkit.NewConfig(opts...)
+	classifier := cfg.Classifier(otelkit.ClientStatus)
+	return func(next http.RoundTripper) http.RoundTripper {
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return &transport{
+			next:       next,
+			tracer:     cfg.Tracer(ScopeName),
+			cfg:        cfg,
+			classifier: classifier,
+		}
+	}
+}
+
+type transport struct {
+	next       http.RoundTripper
+	tracer     trace.Tracer
+	cfg        *otelkit.Config
+	classifier otelkit.StatusClassifier
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cfg.Instrumented(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, span := t.tracer.Start(
+		req.Context(),
+		t.cfg.SpanName(t.cfg.OperationName, req),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.cfg.Attributes...))
+
+	span.SetAttributes(httpconv.ClientRequest(req)...)
+	span.SetAttributes(t.cfg.RequestHeaderAttributes(req.Header)...)
+
+	// RoundTrip must not modify the caller's request, clone it before injecting
+	// propagation headers.
+	req = req.Clone(ctx)
+	t.cfg.Propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		otelkit.SetSpanStatus(span, t.classifier, 0, err)
+		span.End()
+		return resp, err
+	}
+
+	span.SetAttributes(httpconv.ClientResponse(resp)...)
+	otelkit.SetSpanStatus(span, t.classifier, resp.StatusCode, nil)
+	span.SetAttributes(t.cfg.ResponseHeaderAttributes(resp.Header)...)
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		span.End()
+		return resp, nil
+	}
+
+	body := &tracedBody{ReadCloser: resp.Body, span: span, classifier: t.classifier}
+	if rw, ok := resp.Body.(io.ReadWriteCloser); ok {
+		// protocol upgrades (101 Switching Protocols) hand out a writable body,
+		// keep it writable.
+		resp.Body = &tracedReadWriteBody{tracedBody: body, w: rw}
+	} else {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+// tracedBody ends the span once the body is drained or closed.
+type tracedBody struct {
+	io.ReadCloser
+	span       trace.Span
+	classifier otelkit.StatusClassifier
+	once       sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch err {
+	case nil:
+	case io.EOF:
+		b.end()
+	default:
+		b.span.RecordError(err)
+		otelkit.SetSpanStatus(b.span, b.classifier, 0, err)
+		b.end()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.end()
+	return err
+}
+
+func (b *tracedBody) end() {
+	b.once.Do(func() {
+		b.span.End()
+	})
+}
+
+type tracedReadWriteBody struct {
+	*tracedBody
+	w io.Writer
+}
+
+func (b *tracedReadWriteBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
@@ -0,0 +1,24 @@
+package kit
+
+import (
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceServerBefore starts server spans with tr.
+//
+// Deprecated: use NewTraceServerBefore(otelkit.WithTracer(tr),
+// otelkit.WithPropagators(propagator)).
+func TraceServerBefore(tr trace.Tracer, propagator propagation.TextMapPropagator) khttp.ServerOption {
+	return NewTraceServerBefore(otelkit.WithTracer(tr), otelkit.WithPropagators(propagator))
+}
+
+// TraceClientBefore starts client spans with tr.
+//
+// Deprecated: use NewTraceClientBefore(otelkit.WithTracer(tr),
+// otelkit.WithPropagators(propagator)).
+func TraceClientBefore(tr trace.Tracer, propagator propagation.TextMapPropagator) khttp.ClientOption {
+	return NewTraceClientBefore(otelkit.WithTracer(tr), otelkit.WithPropagators(propagator))
+}
@@ -0,0 +1,44 @@
+package kit
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/kitphase"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceEndpoint returns an endpoint.Middleware starting an internal span named
+// operation around every invocation of the endpoint, whatever transport
+// serves it. Errors returned by the endpoint, and business errors reported by
+// responses implementing endpoint.Failer, are recorded on the span.
+func TraceEndpoint(operation string, opts ...otelkit.Option) endpoint.Middleware {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	classifier := cfg.Classifier(otelkit.ServerStatus)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			leave := kitphase.EnterEndpoint(ctx)
+			ctx, span := tr.Start(ctx, operation,
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(cfg.Attributes...),
+				trace.WithAttributes(attribute.String("gokit.endpoint", operation)))
+			defer span.End()
+
+			response, err := next(ctx, request)
+			leave(err)
+			if err != nil {
+				span.RecordError(err)
+				otelkit.SetSpanStatus(span, classifier, 0, err)
+				return response, err
+			}
+			if failer, ok := response.(endpoint.Failer); ok && failer.Failed() != nil {
+				span.RecordError(failer.Failed(), trace.WithAttributes(attribute.Bool("gokit.endpoint.failer", true)))
+				otelkit.SetSpanStatus(span, classifier, 0, failer.Failed())
+			}
+			return response, err
+		}
+	}
+}
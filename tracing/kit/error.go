@@ -0,0 +1,51 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/transport"
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit/internal/kitphase"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceErrorHandler returns a transport.ErrorHandler recording the decode,
+// endpoint and encode errors of a go-kit server on the span started by
+// NewTraceServerBefore. Install it with khttp.ServerErrorHandler.
+//
+// The phase an error happened in is recorded as gokit.error.phase. Decode
+// errors are told by a decoder wrapped by TraceDecodeRequest or
+// metric/kit.MeasureDecodeRequest, endpoint errors by an endpoint wrapped by
+// TraceEndpoint or metric/kit.MeasureEndpoint. Other errors are reported in
+// the unknown phase.
+func TraceErrorHandler() transport.ErrorHandler {
+	return transport.ErrorHandlerFunc(recordError)
+}
+
+// TraceErrorEncoder wraps next, khttp.DefaultErrorEncoder when nil, to record
+// the error it encodes like TraceErrorHandler does. An error seen by both is
+// recorded once.
+func TraceErrorEncoder(next khttp.ErrorEncoder) khttp.ErrorEncoder {
+	if next == nil {
+		next = khttp.DefaultErrorEncoder
+	}
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		recordError(ctx, err)
+		next(ctx, err, w)
+	}
+}
+
+func recordError(ctx context.Context, err error) {
+	span, ok := spanFromContext(ctx)
+	if !ok || !kitphase.First(ctx, ScopeName) {
+		return
+	}
+	phase := kitphase.Phase(ctx)
+	span.RecordError(err, trace.WithAttributes(
+		kitphase.Key.String(phase),
+		attribute.String("error.type", fmt.Sprintf("%T", err))))
+	span.SetAttributes(kitphase.Key.String(phase))
+}
@@ -11,20 +11,13 @@ import (
 	"time"
 
 	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit/setup"
 	"github.com/nnnewb/otelkit/tracing/kit"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/baggage"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	tracesdk "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-const (
-	jaegerUrl = "http://192.168.56.4:14268/api/traces"
-	service   = "kit-client-app"
-)
+const service = "kit-client-app"
 
 type ExampleEndpointRequest struct{}
 type ExampleEndpointResponse struct {
@@ -49,38 +42,27 @@ func decodeExampleResponse(_ context.Context, response *http.Response) (interfac
 }
 
 func main() {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(jaegerUrl)))
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
-
-	tp := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
-		// Record information about this application in a Resource.
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(service),
-		)),
-	)
 	defer func() {
-		err := tp.Shutdown(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
 		if err != nil {
-			log.Printf("shutdown failed, error %+v", err)
+			log.Printf("shutdown returned error %+v", err)
 		}
 	}()
 
-	tr := tp.Tracer(service)
+	tr := otel.Tracer(service)
 	endpointUrl, _ := url.Parse("http://127.0.0.1:9998/hello")
 	client := khttp.NewClient(
 		http.MethodGet,
 		endpointUrl,
 		encodeExampleRequest,
 		decodeExampleResponse,
-		kit.TraceClientBefore(tr, otel.GetTextMapPropagator()),
+		kit.NewTraceClientBefore(),
 		kit.TraceClientAfter(),
 		kit.TraceClientFinalizer(),
 	)
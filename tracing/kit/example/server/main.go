@@ -4,21 +4,15 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/setup"
 	"github.com/nnnewb/otelkit/tracing/kit"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	tracesdk "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-const (
-	url     = "http://192.168.56.4:14268/api/traces"
-	service = "kit-http-app"
-)
+const service = "kit-http-app"
 
 type ExampleEndpointRequest struct{}
 type ExampleEndpointResponse struct {
@@ -40,29 +34,24 @@ func endpoint(ctx context.Context, req interface{}) (interface{}, error) {
 }
 
 func main() {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
+	shutdown, err := setup.Start(context.Background(), setup.WithServiceName(service))
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
-
-	tp := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
-		// Record information about this application in a Resource.
-		tracesdk.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(service),
-		)),
-	)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := shutdown(ctx)
+		if err != nil {
+			log.Printf("shutdown returned error %+v", err)
+		}
+	}()
 
 	svr := khttp.NewServer(
-		endpoint,
-		decodeExampleRequest,
-		khttp.EncodeJSONResponse,
-		kit.TraceServerBefore(tp.Tracer("http-example"), otel.GetTextMapPropagator()),
+		kit.TraceEndpoint("hello")(endpoint),
+		kit.TraceDecodeRequest(decodeExampleRequest),
+		kit.TraceEncodeResponse(khttp.EncodeJSONResponse),
+		kit.NewTraceServerBefore(otelkit.WithOperationName("/hello")),
 		kit.TraceServerAfter(),
 		kit.TraceServerFinalizer())
 
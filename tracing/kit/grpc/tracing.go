@@ -0,0 +1,163 @@
+// Package grpc traces go-kit gRPC transports. The server options need
+// kgrpc.Interceptor, or a grpc server stream in the context, to learn the
+// called method.
+package grpc
+
+import (
+	"context"
+
+	kgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/rpcconv"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ScopeName is the instrumentation scope of the spans created by this package.
+const ScopeName = "github.com/nnnewb/otelkit/tracing/kit/grpc"
+
+type spanKeyT struct{}
+
+// spanKey holds the span started by TraceServerBefore or TraceClientBefore, so
+// the later hooks never end a span they did not start.
+var spanKey spanKeyT
+
+func spanFromContext(ctx context.Context) (trace.Span, bool) {
+	span, ok := ctx.Value(spanKey).(trace.Span)
+	return span, ok
+}
+
+// TraceServerBefore extracts the trace context from the request metadata and
+// starts a server span. otelkit.WithOperationName overrides the span name,
+// which defaults to "package.Service/Method".
+func TraceServerBefore(opts ...otelkit.Option) kgrpc.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	return kgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+		fullMethod := rpcconv.FullMethod(ctx)
+		ctx = cfg.Propagators.Extract(ctx, rpcconv.MetadataCarrier(md))
+		ctx, span := tr.Start(ctx, spanName(cfg, fullMethod),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(cfg.Attributes...))
+		span.SetAttributes(rpcconv.Attributes(fullMethod)...)
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			span.SetAttributes(attribute.String("net.sock.peer.addr", p.Addr.String()))
+		}
+		span.SetAttributes(cfg.RequestMetadataAttributes(md)...)
+		return context.WithValue(ctx, spanKey, span)
+	})
+}
+
+// TraceServerAfter records the response metadata set by the endpoint.
+func TraceServerAfter(opts ...otelkit.Option) kgrpc.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ServerAfter(func(ctx context.Context, header *metadata.MD, trailer *metadata.MD) context.Context {
+		if span, ok := spanFromContext(ctx); ok {
+			span.SetAttributes(cfg.ResponseMetadataAttributes(*header)...)
+		}
+		return ctx
+	})
+}
+
+// TraceServerFinalizer records the gRPC status code and ends the server span.
+func TraceServerFinalizer(opts ...otelkit.Option) kgrpc.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ServerFinalizer(func(ctx context.Context, err error) {
+		span, ok := spanFromContext(ctx)
+		if !ok {
+			return
+		}
+		end(span, cfg, rpcconv.ServerStatus, err)
+	})
+}
+
+// TraceClientBefore starts a client span and injects the trace context into
+// the request metadata.
+func TraceClientBefore(opts ...otelkit.Option) kgrpc.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	return kgrpc.ClientBefore(func(ctx context.Context, md *metadata.MD) context.Context {
+		fullMethod := rpcconv.FullMethod(ctx)
+		ctx, span := tr.Start(ctx, spanName(cfg, fullMethod),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(cfg.Attributes...))
+		span.SetAttributes(rpcconv.Attributes(fullMethod)...)
+
+		if *md == nil {
+			*md = metadata.MD{}
+		}
+		span.SetAttributes(cfg.RequestMetadataAttributes(*md)...)
+		cfg.Propagators.Inject(ctx, rpcconv.MetadataCarrier(*md))
+
+		return context.WithValue(ctx, spanKey, span)
+	})
+}
+
+// TraceClientAfter records the response metadata sent by the server.
+func TraceClientAfter(opts ...otelkit.Option) kgrpc.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ClientAfter(func(ctx context.Context, header metadata.MD, trailer metadata.MD) context.Context {
+		if span, ok := spanFromContext(ctx); ok {
+			span.SetAttributes(cfg.ResponseMetadataAttributes(header)...)
+		}
+		return ctx
+	})
+}
+
+// TraceClientFinalizer records the gRPC status code and ends the client span.
+func TraceClientFinalizer(opts ...otelkit.Option) kgrpc.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	return kgrpc.ClientFinalizer(func(ctx context.Context, err error) {
+		span, ok := spanFromContext(ctx)
+		if !ok {
+			return
+		}
+		end(span, cfg, rpcconv.ClientStatus, err)
+	})
+}
+
+func spanName(cfg *otelkit.Config, fullMethod string) string {
+	if cfg.OperationName != "" {
+		return cfg.OperationName
+	}
+	return rpcconv.SpanName(fullMethod)
+}
+
+// StatusClassifier decides the span status of a call from its gRPC status
+// code and the error it failed with, if any.
+type StatusClassifier func(code codes.Code, err error) (otelcodes.Code, string)
+
+type classifierKeyT struct{}
+
+var classifierKey classifierKeyT
+
+// WithStatusClassifier overrides how gRPC status codes and errors map to span
+// status. otelkit.WithStatusClassifier only applies to HTTP status codes and
+// is ignored here.
+func WithStatusClassifier(classifier StatusClassifier) otelkit.Option {
+	return func(cfg *otelkit.Config) {
+		cfg.SetValue(classifierKey, classifier)
+	}
+}
+
+// end classifies the call with the classifier given by WithStatusClassifier,
+// or def, and ends span.
+func end(span trace.Span, cfg *otelkit.Config, def StatusClassifier, err error) {
+	code := rpcconv.Code(err)
+	span.SetAttributes(rpcconv.StatusCodeKey.Int(int(code)))
+	if err != nil {
+		span.RecordError(err)
+	}
+	classify := def
+	if classifier, ok := cfg.Value(classifierKey).(StatusClassifier); ok && classifier != nil {
+		classify = classifier
+	}
+	if c, desc := classify(code, err); c != otelcodes.Unset {
+		span.SetStatus(c, desc)
+	}
+	span.End()
+}
@@ -0,0 +1,164 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	kgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	tracegrpc "github.com/nnnewb/otelkit/tracing/kit/grpc"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// failures maps the service names asked for to the code the server fails
+// with.
+var failures = map[string]codes.Code{
+	"missing": codes.NotFound,
+	"broken":  codes.Internal,
+}
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	check kgrpc.Handler
+}
+
+func (s healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	_, resp, err := s.check.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*healthpb.HealthCheckResponse), nil
+}
+
+func passthrough(_ context.Context, v interface{}) (interface{}, error) { return v, nil }
+
+// dial serves the health service through go-kit over an in-process listener
+// and returns a go-kit client endpoint calling its Check method.
+func dial(t *testing.T, serverOpts []kgrpc.ServerOption, clientOpts []kgrpc.ClientOption) func(context.Context, string) error {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(kgrpc.Interceptor))
+	healthpb.RegisterHealthServer(srv, healthServer{check: kgrpc.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			if code, ok := failures[request.(*healthpb.HealthCheckRequest).Service]; ok {
+				return nil, status.Error(code, code.String())
+			}
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+		},
+		passthrough, passthrough, serverOpts...)})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	check := kgrpc.NewClient(conn, "grpc.health.v1.Health", "Check",
+		passthrough, passthrough, &healthpb.HealthCheckResponse{}, clientOpts...).Endpoint()
+	return func(ctx context.Context, service string) error {
+		_, err := check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		return err
+	}
+}
+
+func traced(h *otelkittest.Harness, opts ...otelkit.Option) ([]kgrpc.ServerOption, []kgrpc.ClientOption) {
+	opts = h.Options(opts...)
+	return []kgrpc.ServerOption{
+		tracegrpc.TraceServerBefore(opts...),
+		tracegrpc.TraceServerAfter(opts...),
+		tracegrpc.TraceServerFinalizer(opts...),
+	}, []kgrpc.ClientOption{
+		tracegrpc.TraceClientBefore(opts...),
+		tracegrpc.TraceClientAfter(opts...),
+		tracegrpc.TraceClientFinalizer(opts...),
+	}
+}
+
+func TestTrace(t *testing.T) {
+	rpc := []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", "grpc.health.v1.Health"),
+		attribute.String("rpc.method", "Check"),
+	}
+	for _, tt := range []struct {
+		service        string
+		code           codes.Code
+		server, client otelcodes.Code
+	}{
+		{"", codes.OK, otelcodes.Unset, otelcodes.Unset},
+		{"missing", codes.NotFound, otelcodes.Unset, otelcodes.Error},
+		{"broken", codes.Internal, otelcodes.Error, otelcodes.Error},
+	} {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			h := otelkittest.New(t)
+			serverOpts, clientOpts := traced(h)
+			check := dial(t, serverOpts, clientOpts)
+
+			if err := check(context.Background(), tt.service); status.Code(err) != tt.code {
+				t.Fatalf("Check() = %v, want %v", err, tt.code)
+			}
+
+			attrs := append(rpc, attribute.Int("rpc.grpc.status_code", int(tt.code)))
+			server := h.RequireSpan(t, trace.SpanKindServer, "grpc.health.v1.Health/Check", attrs...)
+			client := h.RequireSpan(t, trace.SpanKindClient, "grpc.health.v1.Health/Check", attrs...)
+			if server.Parent().SpanID() != client.SpanContext().SpanID() ||
+				server.SpanContext().TraceID() != client.SpanContext().TraceID() {
+				t.Error("server span is not a child of the client span")
+			}
+			if got := server.Status().Code; got != tt.server {
+				t.Errorf("server status = %v, want %v", got, tt.server)
+			}
+			if got := client.Status().Code; got != tt.client {
+				t.Errorf("client status = %v, want %v", got, tt.client)
+			}
+		})
+	}
+}
+
+func TestTraceGRPCStatusClassifier(t *testing.T) {
+	h := otelkittest.New(t)
+	serverOpts, clientOpts := traced(h,
+		// an HTTP classifier shared with the HTTP middlewares must not see
+		// gRPC codes
+		otelkit.WithStatusClassifier(otelkit.ServerStatus),
+		tracegrpc.WithStatusClassifier(func(code codes.Code, err error) (otelcodes.Code, string) {
+			if code == codes.Internal {
+				return otelcodes.Unset, ""
+			}
+			if err != nil {
+				return otelcodes.Error, "classified"
+			}
+			return otelcodes.Unset, ""
+		}))
+	check := dial(t, serverOpts, clientOpts)
+
+	_ = check(context.Background(), "missing")
+	_ = check(context.Background(), "broken")
+
+	for _, tt := range []struct {
+		code codes.Code
+		want otelcodes.Code
+	}{
+		{codes.NotFound, otelcodes.Error},
+		{codes.Internal, otelcodes.Unset},
+	} {
+		span := h.RequireSpan(t, trace.SpanKindServer, "grpc.health.v1.Health/Check",
+			attribute.Int("rpc.grpc.status_code", int(tt.code)))
+		if got := span.Status().Code; got != tt.want {
+			t.Errorf("%v: status = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package lb traces go-kit client-side load balancing: one span per lb.Retry
+// call and one per attempt, tagged with the instance that served it.
+//
+//	factory = lb.TraceFactory(factory)
+//	balancer := lb.TraceBalancer(kitlb.NewRoundRobin(endpointer), opts...)
+//	ep := lb.TraceRetry(opts...)(kitlb.Retry(3, time.Second, balancer))
+package lb
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	kitlb "github.com/go-kit/kit/sd/lb"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/lbconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans created by this package.
+const ScopeName = "github.com/nnnewb/otelkit/tracing/kit/lb"
+
+// TraceRetry returns an endpoint.Middleware for the endpoint built by
+// lb.Retry, starting a span around the whole call. The span records the
+// number of attempts, and whether the retries were exhausted. It is named
+// by otelkit.WithOperationName, "lb.retry" by default.
+func TraceRetry(opts ...otelkit.Option) endpoint.Middleware {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	name := cfg.OperationName
+	if name == "" {
+		name = "lb.retry"
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx = lbconv.Start(ctx)
+			ctx, span := tr.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(cfg.Attributes...))
+			defer span.End()
+
+			response, err := next(ctx, request)
+			span.SetAttributes(lbconv.AttemptsKey.Int(lbconv.Attempts(ctx)))
+			if err != nil {
+				var retryErr kitlb.RetryError
+				span.SetAttributes(attribute.Bool("gokit.lb.exhausted", errors.As(err, &retryErr)))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return response, err
+		}
+	}
+}
+
+// TraceBalancer wraps b so every endpoint it hands out runs in an attempt
+// span, child of the TraceRetry span, numbered by gokit.lb.attempt. Client
+// spans started by tracing/kit options are children of the attempt.
+//
+// The endpoint is only selected from b when the attempt runs, so that an
+// attempt finding no endpoint is traced too. Its error is returned by the
+// endpoint rather than by Endpoint.
+func TraceBalancer(b kitlb.Balancer, opts ...otelkit.Option) kitlb.Balancer {
+	cfg := otelkit.NewConfig(opts...)
+	return &balancer{next: b, tracer: cfg.Tracer(ScopeName), cfg: cfg}
+}
+
+type balancer struct {
+	next   kitlb.Balancer
+	tracer trace.Tracer
+	cfg    *otelkit.Config
+}
+
+func (b *balancer) Endpoint() (endpoint.Endpoint, error) {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		ctx, n := lbconv.Attempt(ctx)
+		ctx, span := b.tracer.Start(ctx, "lb.attempt",
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithAttributes(b.cfg.Attributes...),
+			trace.WithAttributes(lbconv.AttemptKey.Int(n)))
+		defer span.End()
+
+		e, err := lbconv.Select(ctx, b.next)
+		if err == nil {
+			response, err = e(ctx, request)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return response, err
+	}, nil
+}
+
+// TraceFactory wraps factory so the endpoints it creates record the instance
+// they were created for on the current span, the attempt span when balanced
+// by TraceBalancer.
+func TraceFactory(factory sd.Factory) sd.Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		e, closer, err := factory(instance)
+		if err != nil {
+			return e, closer, err
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			trace.SpanFromContext(ctx).SetAttributes(lbconv.InstanceKey.String(instance))
+			return e(ctx, request)
+		}, closer, nil
+	}
+}
@@ -0,0 +1,121 @@
+package lb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kitlb "github.com/go-kit/kit/sd/lb"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"github.com/nnnewb/otelkit/tracing/kit/lb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errDown = errors.New("instance down")
+
+// instance returns an endpoint created by lb.TraceFactory for name, failing
+// with errDown unless ok.
+func instance(t *testing.T, name string, ok bool) endpoint.Endpoint {
+	factory := lb.TraceFactory(func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(context.Context, interface{}) (interface{}, error) {
+			if !ok {
+				return nil, errDown
+			}
+			return addr, nil
+		}, nil, nil
+	})
+	e, _, err := factory(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// script is a balancer handing out its endpoints in order, a nil one stands
+// for no endpoint.
+type script struct {
+	mu        sync.Mutex
+	endpoints []endpoint.Endpoint
+}
+
+func (s *script) Endpoint() (endpoint.Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.endpoints) == 0 {
+		return nil, kitlb.ErrNoEndpoints
+	}
+	e := s.endpoints[0]
+	s.endpoints = s.endpoints[1:]
+	if e == nil {
+		return nil, kitlb.ErrNoEndpoints
+	}
+	return e, nil
+}
+
+func TestRetry(t *testing.T) {
+	h := otelkittest.New(t)
+	b := &script{endpoints: []endpoint.Endpoint{nil, instance(t, "a", false), instance(t, "b", true)}}
+	ep := lb.TraceRetry(h.Options()...)(kitlb.Retry(3, time.Second, lb.TraceBalancer(b, h.Options()...)))
+
+	response, err := ep(context.Background(), nil)
+	if err != nil || response != "b" {
+		t.Fatalf("got %v, %v; want b", response, err)
+	}
+
+	retry := h.RequireSpan(t, trace.SpanKindInternal, "lb.retry", attribute.Int("gokit.lb.attempts", 3))
+	if retry.Status().Code == codes.Error {
+		t.Errorf("retry span failed: %s", retry.Status().Description)
+	}
+	for _, tt := range []struct {
+		attempt  int
+		instance string
+		err      error
+	}{
+		{1, "", kitlb.ErrNoEndpoints},
+		{2, "a", errDown},
+		{3, "b", nil},
+	} {
+		attrs := []attribute.KeyValue{attribute.Int("gokit.lb.attempt", tt.attempt)}
+		if tt.instance != "" {
+			attrs = append(attrs, attribute.String("gokit.lb.instance", tt.instance))
+		}
+		span := h.RequireSpan(t, trace.SpanKindInternal, "lb.attempt", attrs...)
+		if span.Parent().SpanID() != retry.SpanContext().SpanID() {
+			t.Errorf("attempt %d is not a child of the retry span", tt.attempt)
+		}
+		want := codes.Unset
+		if tt.err != nil {
+			want = codes.Error
+		}
+		if span.Status().Code != want || tt.err != nil && span.Status().Description != tt.err.Error() {
+			t.Errorf("attempt %d status = %v %q, want %v %v", tt.attempt, span.Status().Code, span.Status().Description, want, tt.err)
+		}
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	h := otelkittest.New(t)
+	b := &script{endpoints: []endpoint.Endpoint{instance(t, "a", false)}}
+	ep := lb.TraceRetry(h.Options()...)(kitlb.Retry(2, time.Second, lb.TraceBalancer(b, h.Options()...)))
+
+	if _, err := ep(context.Background(), nil); err == nil {
+		t.Fatal("no error")
+	}
+
+	retry := h.RequireSpan(t, trace.SpanKindInternal, "lb.retry",
+		attribute.Int("gokit.lb.attempts", 2),
+		attribute.Bool("gokit.lb.exhausted", true))
+	if retry.Status().Code != codes.Error {
+		t.Errorf("retry span status = %v, want error", retry.Status().Code)
+	}
+	h.RequireSpan(t, trace.SpanKindInternal, "lb.attempt",
+		attribute.Int("gokit.lb.attempt", 1),
+		attribute.String("gokit.lb.instance", "a"))
+	h.RequireSpan(t, trace.SpanKindInternal, "lb.attempt", attribute.Int("gokit.lb.attempt", 2))
+}
@@ -0,0 +1,70 @@
+package kit
+
+import (
+	"context"
+	"net/http"
+
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/kitphase"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceDecodeRequest wraps dec so decoding the request gets its own "decode"
+// span, child of the server span started by NewTraceServerBefore. Together
+// with TraceEndpoint and TraceEncodeResponse it shows where the time of a call
+// went. Requests without a server span are decoded untraced.
+//
+// Errors of dec are reported in the decode phase by TraceErrorHandler and
+// metric/kit.MeasureErrorHandler.
+func TraceDecodeRequest(dec khttp.DecodeRequestFunc, opts ...otelkit.Option) khttp.DecodeRequestFunc {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	return func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+		done := kitphase.Decoding(ctx)
+		defer func() { done(err) }()
+		if _, ok := spanFromContext(ctx); !ok {
+			return dec(ctx, r)
+		}
+		ctx, span := startPhase(ctx, tr, "decode", cfg)
+		defer span.End()
+
+		request, err = dec(ctx, r)
+		endPhase(span, err)
+		return request, err
+	}
+}
+
+// TraceEncodeResponse wraps enc so encoding the response gets its own
+// "encode" span, child of the server span started by NewTraceServerBefore.
+// Responses without a server span are encoded untraced.
+func TraceEncodeResponse(enc khttp.EncodeResponseFunc, opts ...otelkit.Option) khttp.EncodeResponseFunc {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if _, ok := spanFromContext(ctx); !ok {
+			return enc(ctx, w, response)
+		}
+		ctx, span := startPhase(ctx, tr, "encode", cfg)
+		defer span.End()
+
+		err := enc(ctx, w, response)
+		endPhase(span, err)
+		return err
+	}
+}
+
+func startPhase(ctx context.Context, tr trace.Tracer, phase string, cfg *otelkit.Config) (context.Context, trace.Span) {
+	return tr.Start(ctx, phase,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(cfg.Attributes...))
+}
+
+// endPhase records the error of a failed phase on its span.
+func endPhase(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
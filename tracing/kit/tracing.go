@@ -2,102 +2,141 @@ package kit
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 
 	khttp "github.com/go-kit/kit/transport/http"
-	"go.opentelemetry.io/otel/attribute"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/internal/httpconv"
+	"github.com/nnnewb/otelkit/internal/kitphase"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func TraceServerBefore(tr trace.Tracer, propagator propagation.TextMapPropagator) khttp.ServerOption {
+// ScopeName is the instrumentation scope of the spans created by this package.
+const ScopeName = "github.com/nnnewb/otelkit/tracing/kit"
+
+type spanKeyT struct{}
+
+// spanKey holds the span started by NewTraceServerBefore or
+// NewTraceClientBefore, so the later hooks never end a span they did not
+// start, e.g. when the request was filtered out.
+var spanKey spanKeyT
+
+func spanFromContext(ctx context.Context) (trace.Span, bool) {
+	span, ok := ctx.Value(spanKey).(trace.Span)
+	return span, ok
+}
+
+// NewTraceServerBefore starts a server span for every request, child of the
+// trace context propagated by the client, and records the request attributes.
+// go-kit servers know no route template, pass otelkit.WithOperationName to
+// name the span. The span is ended by TraceServerFinalizer.
+func NewTraceServerBefore(opts ...otelkit.Option) khttp.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
 	return khttp.ServerBefore(func(ctx context.Context, request *http.Request) context.Context {
-		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(request.Header))
-		ctx, span := tr.Start(ctx, request.URL.Path)
-		var attrs []attribute.KeyValue
-		for key, values := range request.Header {
-			attrs = append(attrs, attribute.String("http.request.header."+key, strings.Join(values, "\n")))
+		if !cfg.Instrumented(request) {
+			return ctx
 		}
-		span.SetAttributes(
-			attribute.Int64("http.request_content_length", request.ContentLength),
-			attribute.String("http.method", request.Method),
-			attribute.String("net.protocol.name", "http"),
-			attribute.String("net.protocol.version", request.Proto),
-			attribute.String("net.sock.peer.addr", request.RemoteAddr),
-			attribute.String("user_agent.original", request.Header.Get("User-Agent")))
-		span.SetAttributes(attrs...)
-		return ctx
+
+		ctx = kitphase.Start(ctx)
+		ctx = cfg.Propagators.Extract(ctx, propagation.HeaderCarrier(request.Header))
+		ctx, span := tr.Start(ctx, cfg.SpanName(cfg.OperationName, request),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(cfg.Attributes...))
+		span.SetAttributes(httpconv.ServerRequest(request)...)
+		span.SetAttributes(cfg.RequestHeaderAttributes(request.Header)...)
+		// go-kit servers know no route template, the operation name stands in
+		span.SetAttributes(httpconv.ServerRoute(cfg.OperationName)...)
+		return context.WithValue(ctx, spanKey, span)
 	})
 }
 
-func TraceServerAfter() khttp.ServerOption {
+// TraceServerAfter marks the endpoint as done, later errors are reported in
+// the encode phase. The response headers are recorded by TraceServerFinalizer
+// once the encoder set them. It records nothing itself, opts are accepted so
+// all the hooks can be passed the same ones.
+func TraceServerAfter(opts ...otelkit.Option) khttp.ServerOption {
 	return khttp.ServerAfter(func(ctx context.Context, wr http.ResponseWriter) context.Context {
-		span := trace.SpanFromContext(ctx)
-		var attrs []attribute.KeyValue
-		for key, values := range wr.Header() {
-			attrs = append(attrs, attribute.String("http.response.header."+key, strings.Join(values, "\n")))
-		}
-		span.SetAttributes(attrs...)
+		kitphase.Encoding(ctx)
 		return ctx
 	})
 }
 
-func TraceServerFinalizer() khttp.ServerOption {
+// TraceServerFinalizer records the response status, size and headers on the
+// span started by NewTraceServerBefore, sets its status and ends it.
+func TraceServerFinalizer(opts ...otelkit.Option) khttp.ServerOption {
+	cfg := otelkit.NewConfig(opts...)
+	classifier := cfg.Classifier(otelkit.ServerStatus)
 	return khttp.ServerFinalizer(func(ctx context.Context, code int, req *http.Request) {
-		span := trace.SpanFromContext(ctx)
-		span.SetAttributes(attribute.Int("http.status_code", code))
-		if span != nil {
-			span.End()
+		span, ok := spanFromContext(ctx)
+		if !ok {
+			return
 		}
+		if header, ok := ctx.Value(khttp.ContextKeyResponseHeaders).(http.Header); ok {
+			span.SetAttributes(cfg.ResponseHeaderAttributes(header)...)
+		}
+		size, _ := ctx.Value(khttp.ContextKeyResponseSize).(int64)
+		span.SetAttributes(httpconv.ServerResponse(code, size)...)
+		otelkit.SetSpanStatus(span, classifier, code, nil)
+		span.End()
 	})
 }
 
-func TraceClientBefore(tr trace.Tracer, propagator propagation.TextMapPropagator) khttp.ClientOption {
+// NewTraceClientBefore starts a client span for every outbound request and
+// injects its trace context into the request headers. The span is named by
+// otelkit.WithOperationName and ended by TraceClientFinalizer.
+func NewTraceClientBefore(opts ...otelkit.Option) khttp.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	tr := cfg.Tracer(ScopeName)
 	return khttp.ClientBefore(func(ctx context.Context, request *http.Request) context.Context {
-		ctx, span := tr.Start(ctx, request.Method+" "+request.URL.String())
-		var attrs []attribute.KeyValue
-		for key, values := range request.Header {
-			attrs = append(attrs, attribute.String("http.request.header."+key, strings.Join(values, "\n")))
+		if !cfg.Instrumented(request) {
+			return ctx
 		}
-		span.SetAttributes(attrs...)
-		var port int
-		portStr := request.URL.Port()
-		if portStr != "" {
-			port, _ = strconv.Atoi(portStr)
-		}
-		span.SetAttributes(
-			attribute.String("http.method", request.Method),
-			attribute.String("http.flavor", fmt.Sprintf("%d.%d", request.ProtoMajor, request.ProtoMinor)),
-			attribute.String("http.url", request.URL.String()),
-			attribute.String("net.sock.peer.name", request.URL.Hostname()),
-			attribute.Int("net.sock.peer.port", port))
 
-		propagator.Inject(ctx, propagation.HeaderCarrier(request.Header))
+		ctx, span := tr.Start(ctx, cfg.SpanName(cfg.OperationName, request),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(cfg.Attributes...))
+		span.SetAttributes(httpconv.ClientRequest(request)...)
+		span.SetAttributes(cfg.RequestHeaderAttributes(request.Header)...)
 
-		return ctx
+		cfg.Propagators.Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+		return context.WithValue(ctx, spanKey, span)
 	})
 }
 
-func TraceClientAfter() khttp.ClientOption {
+// TraceClientAfter records the response status and headers on the span
+// started by NewTraceClientBefore and sets its status.
+func TraceClientAfter(opts ...otelkit.Option) khttp.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	classifier := cfg.Classifier(otelkit.ClientStatus)
 	return khttp.ClientAfter(func(ctx context.Context, response *http.Response) context.Context {
-		span := trace.SpanFromContext(ctx)
-		span.SetAttributes(attribute.Int("http.status_code", response.StatusCode))
-		var attrs = make([]attribute.KeyValue, 0, len(response.Header))
-		for key, values := range response.Header {
-			attrs = append(attrs, attribute.String("http.response.header."+key, strings.Join(values, "\n")))
+		span, ok := spanFromContext(ctx)
+		if !ok {
+			return ctx
 		}
-		span.SetAttributes(attrs...)
+		span.SetAttributes(httpconv.ClientResponse(response)...)
+		otelkit.SetSpanStatus(span, classifier, response.StatusCode, nil)
+		span.SetAttributes(cfg.ResponseHeaderAttributes(response.Header)...)
 		return ctx
 	})
 }
 
-func TraceClientFinalizer() khttp.ClientOption {
+// TraceClientFinalizer records the error the call failed with, if any, on the
+// span started by NewTraceClientBefore and ends it.
+func TraceClientFinalizer(opts ...otelkit.Option) khttp.ClientOption {
+	cfg := otelkit.NewConfig(opts...)
+	classifier := cfg.Classifier(otelkit.ClientStatus)
 	return khttp.ClientFinalizer(func(ctx context.Context, err error) {
-		span := trace.SpanFromContext(ctx)
-		span.RecordError(err)
+		span, ok := spanFromContext(ctx)
+		if !ok {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			otelkit.SetSpanStatus(span, classifier, 0, err)
+		}
 		span.End()
 	})
 }
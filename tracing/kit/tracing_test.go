@@ -0,0 +1,202 @@
+package kit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	khttp "github.com/go-kit/kit/transport/http"
+	"github.com/nnnewb/otelkit"
+	"github.com/nnnewb/otelkit/otelkittest"
+	"github.com/nnnewb/otelkit/tracing/kit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	if r.URL.Query().Get("bad") != "" {
+		return nil, errors.New("bad request")
+	}
+	return r.URL.Path, nil
+}
+
+func newServer(h *otelkittest.Harness, e func(context.Context, interface{}) (interface{}, error)) *khttp.Server {
+	return khttp.NewServer(
+		kit.TraceEndpoint("hello", h.Options()...)(e),
+		kit.TraceDecodeRequest(decodeRequest, h.Options()...),
+		kit.TraceEncodeResponse(khttp.EncodeJSONResponse, h.Options()...),
+		kit.NewTraceServerBefore(h.Options(otelkit.WithOperationName("/hello"))...),
+		kit.TraceServerAfter(h.Options()...),
+		kit.TraceServerFinalizer(h.Options()...),
+		khttp.ServerErrorHandler(kit.TraceErrorHandler()),
+		khttp.ServerErrorEncoder(kit.TraceErrorEncoder(nil)))
+}
+
+func hello(context.Context, interface{}) (interface{}, error) {
+	return map[string]string{"msg": "hello"}, nil
+}
+
+func TestTraceServer(t *testing.T) {
+	h := otelkittest.New(t)
+	newServer(h, hello).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	h.RequireSpanCount(t, 4)
+	server := h.RequireSpan(t, trace.SpanKindServer, "GET /hello",
+		attribute.String("http.method", http.MethodGet),
+		attribute.Int("http.status_code", http.StatusOK))
+	for _, name := range []string{"decode", "hello", "encode"} {
+		span := h.RequireSpan(t, trace.SpanKindInternal, name)
+		if span.Parent().SpanID() != server.SpanContext().SpanID() {
+			t.Errorf("%s span is not a child of the server span", name)
+		}
+	}
+}
+
+func TestTraceServerErrorPhases(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		target   string
+		endpoint func(context.Context, interface{}) (interface{}, error)
+		phase    string
+	}{
+		{"decode", "/hello?bad=1", hello, "decode"},
+		{"endpoint", "/hello", func(context.Context, interface{}) (interface{}, error) {
+			return nil, errors.New("endpoint failed")
+		}, "endpoint"},
+		{"encode", "/hello", func(context.Context, interface{}) (interface{}, error) {
+			return func() {}, nil // not JSON encodable
+		}, "encode"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := otelkittest.New(t)
+			newServer(h, tt.endpoint).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			span := h.RequireSpan(t, trace.SpanKindServer, "GET /hello",
+				attribute.String("gokit.error.phase", tt.phase),
+				attribute.Int("http.status_code", http.StatusInternalServerError))
+			if span.Status().Code != codes.Error {
+				t.Errorf("status = %v, want error", span.Status().Code)
+			}
+			var exceptions int
+			for _, event := range span.Events() {
+				if event.Name == "exception" {
+					exceptions++
+				}
+			}
+			if exceptions != 1 {
+				t.Errorf("recorded %d errors, want 1", exceptions)
+			}
+		})
+	}
+}
+
+func TestTraceServerErrorUnknownPhase(t *testing.T) {
+	h := otelkittest.New(t)
+	// decoder errors can't be told from others without TraceDecodeRequest
+	srv := khttp.NewServer(hello, decodeRequest, khttp.EncodeJSONResponse,
+		kit.NewTraceServerBefore(h.Options(otelkit.WithOperationName("/hello"))...),
+		kit.TraceServerFinalizer(h.Options()...),
+		khttp.ServerErrorHandler(kit.TraceErrorHandler()))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello?bad=1", nil))
+
+	h.RequireSpan(t, trace.SpanKindServer, "GET /hello",
+		attribute.String("gokit.error.phase", "unknown"))
+}
+
+func TestTraceClientPropagates(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(newServer(h, hello))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/hello")
+	client := khttp.NewClient(http.MethodGet, u,
+		khttp.EncodeJSONRequest,
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.NewTraceClientBefore(h.Options(otelkit.WithOperationName("hello"))...),
+		kit.TraceClientAfter(h.Options()...),
+		kit.TraceClientFinalizer(h.Options()...))
+	if _, err := client.Endpoint()(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	clientSpan := h.RequireSpan(t, trace.SpanKindClient, "hello",
+		attribute.Int("http.status_code", http.StatusOK))
+	serverSpan := h.RequireSpan(t, trace.SpanKindServer, "GET /hello")
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Errorf("server span parent = %s, want client span %s",
+			serverSpan.Parent().SpanID(), clientSpan.SpanContext().SpanID())
+	}
+}
+
+func TestTraceClientError(t *testing.T) {
+	h := otelkittest.New(t)
+	u, _ := url.Parse("http://127.0.0.1:1/hello")
+	client := khttp.NewClient(http.MethodGet, u,
+		khttp.EncodeJSONRequest,
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.NewTraceClientBefore(h.Options()...),
+		kit.TraceClientAfter(h.Options()...),
+		kit.TraceClientFinalizer(h.Options()...))
+	if _, err := client.Endpoint()(context.Background(), struct{}{}); err == nil {
+		t.Fatal("want a connection error")
+	}
+
+	span := h.RequireSpan(t, trace.SpanKindClient, "GET")
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", span.Status().Code)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	h := otelkittest.New(t)
+	srv := httptest.NewServer(newServer(h, hello))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL + "/hello")
+	client := khttp.NewClient(http.MethodGet, u,
+		khttp.EncodeJSONRequest,
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.NewTraceClientBefore(h.Options(otelkit.WithOperationName("hello"))...),
+		kit.TraceClientAfter(h.Options()...),
+		kit.TraceClientFinalizer(h.Options()...))
+	if _, err := client.Endpoint()(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.RequireGolden(t, "golden")
+}
+
+func TestDeprecated(t *testing.T) {
+	h := otelkittest.New(t)
+	tracer := h.TracerProvider.Tracer("legacy")
+	srv := httptest.NewServer(khttp.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return nil, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		kit.TraceServerBefore(tracer, propagation.TraceContext{}),
+		kit.TraceServerFinalizer()))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	client := khttp.NewClient(http.MethodGet, u,
+		func(context.Context, *http.Request, interface{}) error { return nil },
+		func(context.Context, *http.Response) (interface{}, error) { return nil, nil },
+		kit.TraceClientBefore(tracer, propagation.TraceContext{}),
+		kit.TraceClientAfter(),
+		kit.TraceClientFinalizer())
+
+	if _, err := client.Endpoint()(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	server := h.RequireSpan(t, trace.SpanKindServer, "GET", attribute.Int("http.status_code", http.StatusOK))
+	clientSpan := h.RequireSpan(t, trace.SpanKindClient, "GET", attribute.Int("http.status_code", http.StatusOK))
+	if server.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("server span is not a child of the client span")
+	}
+}